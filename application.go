@@ -9,11 +9,15 @@ import (
 
 // Application for managing routers.
 type Application struct {
-	defaultRouter *Router            // default router.
-	routers       map[string]*Router // routers.
-	sessionStore  sessions.Store     // default session store.
-	logger        fasthttp.Logger    // default logger.
-	Config        *Config            // configuration.
+	defaultRouter  *Router            // default router.
+	routers        map[string]*Router // routers.
+	sessionStore   sessions.Store     // default session store.
+	logger         fasthttp.Logger    // default logger.
+	errorHandler   ErrorHandler       // default error handler.
+	policy         Policy             // default authorization policy.
+	trustedProxies *TrustedProxies    // trusted proxies.
+	secretKeys     [][]byte           // secret keys of cookies.
+	Config         *Config            // configuration.
 }
 
 // NewApplication returns an application's instance.
@@ -40,6 +44,27 @@ func (a *Application) SetSessionStore(store sessions.Store) {
 	a.sessionStore = store
 }
 
+// SetErrorHandler for setting error handler.
+func (a *Application) SetErrorHandler(handler ErrorHandler) {
+	a.errorHandler = handler
+}
+
+// SetPolicy for setting authorization policy.
+func (a *Application) SetPolicy(policy Policy) {
+	a.policy = policy
+}
+
+// SetTrustedProxies for setting trusted proxies,
+// which are used to resolve the host of the request for routing.
+func (a *Application) SetTrustedProxies(proxies *TrustedProxies) {
+	a.trustedProxies = proxies
+}
+
+// SetSecretKeys for setting secret keys of signed and encrypted cookies, see Router.SetSecretKeys.
+func (a *Application) SetSecretKeys(keys ...[]byte) {
+	a.secretKeys = keys
+}
+
 // NewRouter returns a new Router's instance.
 //
 // Set the current router as default router if the domain is an empty string.
@@ -47,6 +72,12 @@ func (a *Application) NewRouter(domain string) *Router {
 	r := NewRouter()
 	r.sessionStore = a.sessionStore
 	r.logger = a.logger
+	r.errorHandler = a.errorHandler
+	r.policy = a.policy
+	r.trustedProxies = a.trustedProxies
+	if len(a.secretKeys) > 0 {
+		r.SetSecretKeys(a.secretKeys...)
+	}
 	a.routers[domain] = r
 	// Set the current router as default, if the domain is an empty string.
 	if len(domain) == 0 {
@@ -82,7 +113,7 @@ func (a *Application) getHandler() func(ctx *fasthttp.RequestCtx) {
 
 // Handler returns application' Handler.
 func (a *Application) Handler(ctx *fasthttp.RequestCtx) {
-	host := strings.Split(string(ctx.Host()), ":")
+	host := strings.Split(a.trustedProxies.resolve(ctx).host, ":")
 	if r, ok := a.routers[host[0]]; ok {
 		r.Handler(ctx)
 		return
@@ -101,29 +132,31 @@ func (a *Application) Run() {
 
 	info()
 
+	server := &fasthttp.Server{
+		Handler:            handler,
+		MaxRequestBodySize: a.Config.ServerMaxRequestBodySize,
+		StreamRequestBody:  a.Config.ServerStreamRequestBody,
+	}
 	switch a.Config.ServerType {
 	case ServerTypeUNIX:
-		log.Fatal(fasthttp.ListenAndServeUNIX(
+		log.Fatal(server.ListenAndServeUNIX(
 			a.Config.ServerAddr,
 			a.Config.ServerMode,
-			handler,
 		))
 	case ServerTypeTLS:
-		log.Fatal(fasthttp.ListenAndServeTLS(
+		log.Fatal(server.ListenAndServeTLS(
 			a.Config.ServerAddr,
 			a.Config.ServerCertFile,
 			a.Config.ServerKeyFile,
-			handler,
 		))
 	case ServerTypeTLSEmbed:
-		log.Fatal(fasthttp.ListenAndServeTLSEmbed(
+		log.Fatal(server.ListenAndServeTLSEmbed(
 			a.Config.ServerAddr,
 			a.Config.ServerCertData,
 			a.Config.ServerKeyData,
-			handler,
 		))
 	default:
-		log.Fatal(fasthttp.ListenAndServe(a.Config.ServerAddr, handler))
+		log.Fatal(server.ListenAndServe(a.Config.ServerAddr))
 	}
 
 }
@@ -0,0 +1,307 @@
+package clevergo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrMissingCredentials means that the request doesn't contain credentials.
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials means that the request's credentials are invalid.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// BasicAuthValidator returns the principal of the username and password.
+//
+// The credentials are invalid if the principal is nil or the error is non-nil.
+type BasicAuthValidator func(ctx *Context, username, password string) (interface{}, error)
+
+// TokenValidator returns the principal of the token, such as bearer token and API key.
+//
+// The token is invalid if the principal is nil or the error is non-nil.
+type TokenValidator func(ctx *Context, token string) (interface{}, error)
+
+// Principal returns the authenticated principal of the current request,
+// it is set by the authentication middlewares.
+//
+// Returns nil if the request is not authenticated.
+func (ctx *Context) Principal() interface{} {
+	ctx.checkReleased()
+	return ctx.principal
+}
+
+// PrincipalIdentifier is implemented by the principals which have a stable identity, such as user ID,
+// see RateLimitByPrincipal.
+type PrincipalIdentifier interface {
+	PrincipalID() string
+}
+
+// SetPrincipal sets the authenticated principal of the current request.
+func (ctx *Context) SetPrincipal(principal interface{}) {
+	ctx.checkReleased()
+	ctx.principal = principal
+}
+
+// BasicAuthConfig for BasicAuthMiddleware.
+type BasicAuthConfig struct {
+	Realm     string             // Realm of WWW-Authenticate challenge.
+	Validator BasicAuthValidator // Validator of username and password.
+}
+
+// NewBasicAuthConfig returns default HTTP Basic authentication configuration.
+func NewBasicAuthConfig(validator BasicAuthValidator) *BasicAuthConfig {
+	return &BasicAuthConfig{
+		Realm:     "Restricted",
+		Validator: validator,
+	}
+}
+
+// BasicAuthMiddleware authenticates the request by HTTP Basic authentication.
+//
+// The unauthenticated requests will be handled by Context.HandleError with status code 401,
+// and the WWW-Authenticate challenge.
+type BasicAuthMiddleware struct {
+	config *BasicAuthConfig
+}
+
+// NewBasicAuthMiddleware returns a BasicAuthMiddleware's instance.
+func NewBasicAuthMiddleware(config *BasicAuthConfig) *BasicAuthMiddleware {
+	return &BasicAuthMiddleware{config: config}
+}
+
+// Handle implemented Middleware Interface.
+func (m *BasicAuthMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		username, password, ok := parseBasicAuth(ctx.Request.Header.Peek("Authorization"))
+		if !ok {
+			m.unauthorized(ctx, ErrMissingCredentials)
+			return
+		}
+
+		principal, err := m.config.Validator(ctx, username, password)
+		if err != nil || principal == nil {
+			m.unauthorized(ctx, authError(err))
+			return
+		}
+
+		ctx.SetPrincipal(principal)
+		next.Handle(ctx)
+	})
+}
+
+func (m *BasicAuthMiddleware) unauthorized(ctx *Context, err error) {
+	ctx.HandleError(fasthttp.StatusUnauthorized, err)
+	ctx.Response.Header.Set("WWW-Authenticate", "Basic realm="+strconv.Quote(m.config.Realm)+`, charset="UTF-8"`)
+}
+
+// parseBasicAuth parses the username and password of the Authorization header.
+func parseBasicAuth(auth []byte) (string, string, bool) {
+	const prefix = "basic "
+	if len(auth) <= len(prefix) || !strings.EqualFold(string(auth[:len(prefix)]), prefix) {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(string(auth[len(prefix):]))
+	if err != nil {
+		return "", "", false
+	}
+	i := bytes.IndexByte(decoded, ':')
+	if i < 0 {
+		return "", "", false
+	}
+	return string(decoded[:i]), string(decoded[i+1:]), true
+}
+
+// BasicAuthUsers returns a BasicAuthValidator of the static username and password pairs,
+// the principal is the username.
+//
+// The credentials are compared in constant time.
+func BasicAuthUsers(users map[string]string) BasicAuthValidator {
+	return func(ctx *Context, username, password string) (interface{}, error) {
+		matched := ""
+		for u, p := range users {
+			// Compare all pairs, so that the time doesn't depend on which user matches.
+			if secureCompare(username, u) && secureCompare(password, p) {
+				matched = u
+			}
+		}
+		if matched == "" {
+			return nil, ErrInvalidCredentials
+		}
+		return matched, nil
+	}
+}
+
+// BearerAuthConfig for BearerAuthMiddleware.
+type BearerAuthConfig struct {
+	Realm     string         // Realm of WWW-Authenticate challenge.
+	Validator TokenValidator // Validator of bearer token.
+}
+
+// NewBearerAuthConfig returns default bearer token authentication configuration.
+func NewBearerAuthConfig(validator TokenValidator) *BearerAuthConfig {
+	return &BearerAuthConfig{
+		Realm:     "Restricted",
+		Validator: validator,
+	}
+}
+
+// BearerAuthMiddleware authenticates the request by bearer token (RFC 6750).
+//
+// The unauthenticated requests will be handled by Context.HandleError with status code 401,
+// and the WWW-Authenticate challenge.
+type BearerAuthMiddleware struct {
+	config *BearerAuthConfig
+}
+
+// NewBearerAuthMiddleware returns a BearerAuthMiddleware's instance.
+func NewBearerAuthMiddleware(config *BearerAuthConfig) *BearerAuthMiddleware {
+	return &BearerAuthMiddleware{config: config}
+}
+
+// Handle implemented Middleware Interface.
+func (m *BearerAuthMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		token, ok := parseBearerToken(ctx.Request.Header.Peek("Authorization"))
+		if !ok {
+			m.unauthorized(ctx, ErrMissingCredentials)
+			return
+		}
+
+		principal, err := m.config.Validator(ctx, token)
+		if err != nil || principal == nil {
+			m.unauthorized(ctx, authError(err))
+			return
+		}
+
+		ctx.SetPrincipal(principal)
+		next.Handle(ctx)
+	})
+}
+
+func (m *BearerAuthMiddleware) unauthorized(ctx *Context, err error) {
+	ctx.HandleError(fasthttp.StatusUnauthorized, err)
+	challenge := "Bearer realm=" + strconv.Quote(m.config.Realm)
+	if err != ErrMissingCredentials {
+		challenge += `, error="invalid_token"`
+	}
+	ctx.Response.Header.Set("WWW-Authenticate", challenge)
+}
+
+// parseBearerToken parses the bearer token of the Authorization header.
+func parseBearerToken(auth []byte) (string, bool) {
+	const prefix = "bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(string(auth[:len(prefix)]), prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(string(auth[len(prefix):]))
+	return token, token != ""
+}
+
+// APIKeyConfig for APIKeyMiddleware.
+type APIKeyConfig struct {
+	Realm      string         // Realm of WWW-Authenticate challenge.
+	Header     string         // Name of header which contains the API key, empty means disabled.
+	QueryParam string         // Name of query parameter which contains the API key, empty means disabled.
+	Validator  TokenValidator // Validator of API key.
+}
+
+// NewAPIKeyConfig returns default API key authentication configuration,
+// which reads the API key from the X-API-Key header.
+func NewAPIKeyConfig(validator TokenValidator) *APIKeyConfig {
+	return &APIKeyConfig{
+		Realm:     "Restricted",
+		Header:    "X-API-Key",
+		Validator: validator,
+	}
+}
+
+// APIKeyMiddleware authenticates the request by API key in header or query string,
+// the header takes precedence over the query parameter.
+//
+// The unauthenticated requests will be handled by Context.HandleError with status code 401,
+// and the WWW-Authenticate challenge.
+type APIKeyMiddleware struct {
+	config *APIKeyConfig
+}
+
+// NewAPIKeyMiddleware returns an APIKeyMiddleware's instance.
+func NewAPIKeyMiddleware(config *APIKeyConfig) *APIKeyMiddleware {
+	return &APIKeyMiddleware{config: config}
+}
+
+// Handle implemented Middleware Interface.
+func (m *APIKeyMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		var key []byte
+		if m.config.Header != "" {
+			key = ctx.Request.Header.Peek(m.config.Header)
+		}
+		if len(key) == 0 && m.config.QueryParam != "" {
+			key = ctx.QueryArgs().Peek(m.config.QueryParam)
+		}
+		if len(key) == 0 {
+			m.unauthorized(ctx, ErrMissingCredentials)
+			return
+		}
+
+		principal, err := m.config.Validator(ctx, string(key))
+		if err != nil || principal == nil {
+			m.unauthorized(ctx, authError(err))
+			return
+		}
+
+		ctx.SetPrincipal(principal)
+		next.Handle(ctx)
+	})
+}
+
+func (m *APIKeyMiddleware) unauthorized(ctx *Context, err error) {
+	ctx.HandleError(fasthttp.StatusUnauthorized, err)
+	challenge := "APIKey realm=" + strconv.Quote(m.config.Realm)
+	if m.config.Header != "" {
+		challenge += ", header=" + strconv.Quote(m.config.Header)
+	}
+	ctx.Response.Header.Set("WWW-Authenticate", challenge)
+}
+
+// StaticTokens returns a TokenValidator of the static tokens, the principal is the value of token.
+//
+// The tokens are compared in constant time.
+func StaticTokens(tokens map[string]interface{}) TokenValidator {
+	return func(ctx *Context, token string) (interface{}, error) {
+		var matched interface{}
+		for t, principal := range tokens {
+			// Compare all tokens, so that the time doesn't depend on which token matches.
+			if secureCompare(token, t) {
+				matched = principal
+			}
+		}
+		if matched == nil {
+			return nil, ErrInvalidCredentials
+		}
+		return matched, nil
+	}
+}
+
+// secureCompare compares the strings in constant time,
+// the strings are hashed first so that the time doesn't leak the length.
+func secureCompare(given, actual string) bool {
+	g, a := sha256.Sum256([]byte(given)), sha256.Sum256([]byte(actual))
+	return subtle.ConstantTimeCompare(g[:], a[:]) == 1
+}
+
+// authError returns the error of validation, or ErrInvalidCredentials if it is nil.
+func authError(err error) error {
+	if err == nil {
+		return ErrInvalidCredentials
+	}
+	return err
+}
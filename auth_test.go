@@ -0,0 +1,127 @@
+package clevergo
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func newAuthRouter(m Middleware) *Router {
+	r := NewRouter()
+	r.AddMiddleware(m)
+	r.GET("/", HandlerFunc(func(ctx *Context) {
+		ctx.Textf("%v", ctx.Principal())
+	}))
+	return r
+}
+
+func TestBasicAuthMiddleware(t *testing.T) {
+	r := newAuthRouter(NewBasicAuthMiddleware(NewBasicAuthConfig(BasicAuthUsers(map[string]string{
+		"foo": "bar",
+		"baz": "qux",
+	}))))
+
+	basic := func(credentials string) string {
+		return "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(credentials)) + "\r\n"
+	}
+	tests := []struct {
+		header string
+		code   int
+		body   string
+	}{
+		{basic("foo:bar"), 200, "foo"},
+		{basic("baz:qux"), 200, "baz"},
+		{"Authorization: basic " + base64.StdEncoding.EncodeToString([]byte("foo:bar")) + "\r\n", 200, "foo"},
+		{basic("foo:qux"), 401, ""},
+		{basic("foo"), 401, ""},
+		{"Authorization: Basic !!!\r\n", 401, ""},
+		{"", 401, ""},
+	}
+	for _, test := range tests {
+		resp := serve(t, r.Handler, "GET / HTTP/1.1\r\n"+test.header+"\r\n")
+		if resp.StatusCode() != test.code {
+			t.Errorf("%q: unexpected status code %d. Expected %d", test.header, resp.StatusCode(), test.code)
+		}
+		if test.code == 200 && string(resp.Body()) != test.body {
+			t.Errorf("%q: unexpected principal %q. Expected %q", test.header, resp.Body(), test.body)
+		}
+		if challenge := string(resp.Header.Peek("WWW-Authenticate")); test.code == 401 && challenge != `Basic realm="Restricted", charset="UTF-8"` {
+			t.Errorf("%q: unexpected challenge %q", test.header, challenge)
+		}
+	}
+}
+
+func TestBearerAuthMiddleware(t *testing.T) {
+	validator := func(ctx *Context, token string) (interface{}, error) {
+		if token == "error" {
+			return nil, errors.New("database error")
+		}
+		return StaticTokens(map[string]interface{}{"secret": "user-1"})(ctx, token)
+	}
+	r := newAuthRouter(NewBearerAuthMiddleware(NewBearerAuthConfig(validator)))
+
+	var handled error
+	r.SetErrorHandler(func(ctx *Context, code int, err error) {
+		handled = err
+		ctx.Error(err.Error(), code)
+	})
+
+	tests := []struct {
+		header    string
+		code      int
+		challenge string
+		err       error
+	}{
+		{"Authorization: Bearer secret\r\n", 200, "", nil},
+		{"Authorization: Bearer wrong\r\n", 401, `Bearer realm="Restricted", error="invalid_token"`, ErrInvalidCredentials},
+		{"Authorization: Bearer \r\n", 401, `Bearer realm="Restricted"`, ErrMissingCredentials},
+		{"", 401, `Bearer realm="Restricted"`, ErrMissingCredentials},
+	}
+	for _, test := range tests {
+		handled = nil
+		resp := serve(t, r.Handler, "GET / HTTP/1.1\r\n"+test.header+"\r\n")
+		if resp.StatusCode() != test.code {
+			t.Errorf("%q: unexpected status code %d. Expected %d", test.header, resp.StatusCode(), test.code)
+		}
+		if challenge := string(resp.Header.Peek("WWW-Authenticate")); challenge != test.challenge {
+			t.Errorf("%q: unexpected challenge %q. Expected %q", test.header, challenge, test.challenge)
+		}
+		if handled != test.err {
+			t.Errorf("%q: unexpected error %v. Expected %v", test.header, handled, test.err)
+		}
+	}
+
+	resp := serve(t, r.Handler, "GET / HTTP/1.1\r\nAuthorization: Bearer error\r\n\r\n")
+	if resp.StatusCode() != 401 || string(resp.Body()) != "database error" {
+		t.Errorf("Unexpected response %d %q", resp.StatusCode(), resp.Body())
+	}
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	config := NewAPIKeyConfig(StaticTokens(map[string]interface{}{"key-1": "service-1"}))
+	config.QueryParam = "api_key"
+	r := newAuthRouter(NewAPIKeyMiddleware(config))
+
+	tests := []struct {
+		request string
+		code    int
+	}{
+		{"GET / HTTP/1.1\r\nX-API-Key: key-1\r\n\r\n", 200},
+		{"GET /?api_key=key-1 HTTP/1.1\r\n\r\n", 200},
+		{"GET /?api_key=key-1 HTTP/1.1\r\nX-API-Key: wrong\r\n\r\n", 401},
+		{"GET /?api_key=wrong HTTP/1.1\r\n\r\n", 401},
+		{"GET / HTTP/1.1\r\n\r\n", 401},
+	}
+	for _, test := range tests {
+		resp := serve(t, r.Handler, test.request)
+		if resp.StatusCode() != test.code {
+			t.Errorf("%q: unexpected status code %d. Expected %d", test.request, resp.StatusCode(), test.code)
+		}
+		if test.code == 200 && string(resp.Body()) != "service-1" {
+			t.Errorf("%q: unexpected principal %q", test.request, resp.Body())
+		}
+		if challenge := string(resp.Header.Peek("WWW-Authenticate")); test.code == 401 && challenge != `APIKey realm="Restricted", header="X-API-Key"` {
+			t.Errorf("%q: unexpected challenge %q", test.request, challenge)
+		}
+	}
+}
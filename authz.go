@@ -0,0 +1,237 @@
+package clevergo
+
+import (
+	"errors"
+	"github.com/valyala/fasthttp"
+	"sync"
+)
+
+var (
+	// ErrNoPolicy means that there is no authorization policy for the router.
+	ErrNoPolicy = errors.New("no authorization policy")
+	// ErrUnauthenticated means that the request is not authenticated.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden means that the principal is not allowed to access the resource.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Requirement describes the roles and permissions which are required to access a resource.
+//
+// The principal must have any of the roles and all of the permissions,
+// an empty requirement only requires the request to be authenticated.
+type Requirement struct {
+	Roles       []string // Any of the roles is required.
+	Permissions []string // All of the permissions are required.
+}
+
+// Policy decides whether the principal satisfies the requirement,
+// it is the extension point for the custom policy engines.
+type Policy interface {
+	Authorize(ctx *Context, principal interface{}, requirement Requirement) (bool, error)
+}
+
+// PolicyFunc is an adapter to allow the use of ordinary functions as Policy.
+type PolicyFunc func(ctx *Context, principal interface{}, requirement Requirement) (bool, error)
+
+// Authorize calls f(ctx, principal, requirement).
+func (f PolicyFunc) Authorize(ctx *Context, principal interface{}, requirement Requirement) (bool, error) {
+	return f(ctx, principal, requirement)
+}
+
+// RoleHolder is implemented by the principals which have roles.
+type RoleHolder interface {
+	Roles() []string
+}
+
+// RBAC is the built-in role-based access control Policy.
+//
+// The roles of principal are resolved by RolesOf, the roles inherit the permissions of their parents,
+// and the permission "*" grants all permissions.
+type RBAC struct {
+	// RolesOf returns the roles of the principal, the default implementation
+	// supports RoleHolder and the string principal which is treated as the role.
+	RolesOf func(principal interface{}) []string
+
+	mu          sync.RWMutex
+	permissions map[string]map[string]bool
+	parents     map[string][]string
+}
+
+// NewRBAC returns a RBAC's instance.
+func NewRBAC() *RBAC {
+	return &RBAC{
+		RolesOf:     rolesOf,
+		permissions: make(map[string]map[string]bool),
+		parents:     make(map[string][]string),
+	}
+}
+
+// Grant grants the permissions to the role.
+func (r *RBAC) Grant(role string, permissions ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.permissions[role] == nil {
+		r.permissions[role] = make(map[string]bool)
+	}
+	for _, permission := range permissions {
+		r.permissions[role][permission] = true
+	}
+}
+
+// Inherit makes the role inherit the roles and permissions of the parents.
+func (r *RBAC) Inherit(role string, parents ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.parents[role] = append(r.parents[role], parents...)
+}
+
+// HasRole reports whether the role is or inherits the expected role.
+func (r *RBAC) HasRole(role, expected string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.expand([]string{role})[expected]
+}
+
+// IsGranted reports whether the role has the permission.
+func (r *RBAC) IsGranted(role, permission string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.isGranted(r.expand([]string{role}), permission)
+}
+
+// Authorize implemented Policy Interface.
+func (r *RBAC) Authorize(ctx *Context, principal interface{}, requirement Requirement) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles := r.expand(r.RolesOf(principal))
+	if len(requirement.Roles) > 0 {
+		matched := false
+		for _, role := range requirement.Roles {
+			if roles[role] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	for _, permission := range requirement.Permissions {
+		if !r.isGranted(roles, permission) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// expand returns the roles and their ancestors.
+func (r *RBAC) expand(roles []string) map[string]bool {
+	// Copy the roles, so that the principal's roles are not modified by appending.
+	roles = append([]string{}, roles...)
+	expanded := make(map[string]bool)
+	for len(roles) > 0 {
+		role := roles[len(roles)-1]
+		roles = roles[:len(roles)-1]
+		if expanded[role] {
+			continue
+		}
+		expanded[role] = true
+		roles = append(roles, r.parents[role]...)
+	}
+	return expanded
+}
+
+func (r *RBAC) isGranted(roles map[string]bool, permission string) bool {
+	for role := range roles {
+		if permissions := r.permissions[role]; permissions[permission] || permissions["*"] {
+			return true
+		}
+	}
+	return false
+}
+
+// rolesOf is the default RBAC.RolesOf.
+func rolesOf(principal interface{}) []string {
+	switch p := principal.(type) {
+	case RoleHolder:
+		return p.Roles()
+	case string:
+		return []string{p}
+	}
+	return nil
+}
+
+// Authorize reports whether the principal of the current request satisfies the requirement,
+// by the router's policy.
+//
+// Returns ErrUnauthenticated if there is no principal, ErrForbidden if the requirement
+// is not satisfied, or the error of policy.
+func (ctx *Context) Authorize(requirement Requirement) error {
+	ctx.checkReleased()
+	if ctx.principal == nil {
+		return ErrUnauthenticated
+	}
+	if ctx.router.policy == nil {
+		return ErrNoPolicy
+	}
+
+	allowed, err := ctx.router.policy.Authorize(ctx, ctx.principal, requirement)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// AuthorizeMiddleware checks the requirement by the router's policy,
+// it should be applied after the authentication middlewares.
+//
+// The rejected requests will be handled by Context.HandleError with status code 401 if
+// the request is not authenticated, 403 if the requirement is not satisfied,
+// or 500 if the policy fails.
+type AuthorizeMiddleware struct {
+	requirement Requirement
+}
+
+// NewAuthorizeMiddleware returns an AuthorizeMiddleware's instance.
+func NewAuthorizeMiddleware(requirement Requirement) *AuthorizeMiddleware {
+	return &AuthorizeMiddleware{requirement: requirement}
+}
+
+// RequireRoles returns an AuthorizeMiddleware which requires any of the roles.
+func RequireRoles(roles ...string) *AuthorizeMiddleware {
+	return NewAuthorizeMiddleware(Requirement{Roles: roles})
+}
+
+// RequirePermissions returns an AuthorizeMiddleware which requires all of the permissions.
+func RequirePermissions(permissions ...string) *AuthorizeMiddleware {
+	return NewAuthorizeMiddleware(Requirement{Permissions: permissions})
+}
+
+// Handle implemented Middleware Interface.
+func (m *AuthorizeMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		switch err := ctx.Authorize(m.requirement); err {
+		case nil:
+			next.Handle(ctx)
+		case ErrUnauthenticated:
+			ctx.HandleError(fasthttp.StatusUnauthorized, err)
+		case ErrForbidden:
+			ctx.HandleError(fasthttp.StatusForbidden, err)
+		default:
+			ctx.HandleError(fasthttp.StatusInternalServerError, err)
+		}
+	})
+}
+
+// ControllerRequirements is implemented by the controllers which declare the requirements
+// of their methods, the requirements are checked after the controller's middlewares.
+type ControllerRequirements interface {
+	// Requirements returns the requirements keyed by request method, such as "POST",
+	// or keyed by action for the resource controllers, such as "Create".
+	Requirements() map[string]Requirement
+}
@@ -0,0 +1,203 @@
+package clevergo
+
+import (
+	"errors"
+	"testing"
+)
+
+type testPrincipal struct {
+	name  string
+	roles []string
+}
+
+func (p testPrincipal) Roles() []string {
+	return p.roles
+}
+
+// principalMiddleware authenticates the request by the User header.
+type principalMiddleware map[string]interface{}
+
+func (m principalMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		if principal, ok := m[string(ctx.Request.Header.Peek("User"))]; ok {
+			ctx.SetPrincipal(principal)
+		}
+		next.Handle(ctx)
+	})
+}
+
+var testPrincipals = principalMiddleware{
+	"admin":  testPrincipal{name: "admin", roles: []string{"admin"}},
+	"editor": testPrincipal{name: "editor", roles: []string{"editor"}},
+	"guest":  "guest",
+}
+
+func newTestRBAC() *RBAC {
+	rbac := NewRBAC()
+	rbac.Grant("guest", "posts.read")
+	rbac.Grant("editor", "posts.write")
+	rbac.Inherit("editor", "guest")
+	rbac.Grant("admin", "*")
+	return rbac
+}
+
+func TestRBAC(t *testing.T) {
+	rbac := newTestRBAC()
+	rbac.Inherit("guest", "editor") // Cycle.
+
+	tests := []struct {
+		role       string
+		permission string
+		granted    bool
+	}{
+		{"guest", "posts.read", true},
+		{"guest", "posts.write", true},
+		{"editor", "posts.read", true},
+		{"admin", "posts.delete", true},
+		{"editor", "posts.delete", false},
+		{"unknown", "posts.read", false},
+	}
+	for _, test := range tests {
+		if granted := rbac.IsGranted(test.role, test.permission); granted != test.granted {
+			t.Errorf("%s %s: expected %t, got %t", test.role, test.permission, test.granted, granted)
+		}
+	}
+
+	if !rbac.HasRole("editor", "guest") || rbac.HasRole("admin", "guest") {
+		t.Error("unexpected role inheritance")
+	}
+}
+
+func TestRBAC_Authorize(t *testing.T) {
+	rbac := newTestRBAC()
+	tests := []struct {
+		principal   interface{}
+		requirement Requirement
+		allowed     bool
+	}{
+		{testPrincipals["admin"], Requirement{Roles: []string{"admin"}}, true},
+		{testPrincipals["editor"], Requirement{Roles: []string{"admin", "guest"}}, true},
+		{testPrincipals["editor"], Requirement{Roles: []string{"admin"}}, false},
+		{testPrincipals["editor"], Requirement{Permissions: []string{"posts.read", "posts.write"}}, true},
+		{testPrincipals["guest"], Requirement{Permissions: []string{"posts.read", "posts.write"}}, false},
+		{testPrincipals["guest"], Requirement{}, true},
+		{42, Requirement{Roles: []string{"guest"}}, false},
+	}
+	for i, test := range tests {
+		if allowed, err := rbac.Authorize(nil, test.principal, test.requirement); err != nil || allowed != test.allowed {
+			t.Errorf("%d: expected %t, got %t, %v", i, test.allowed, allowed, err)
+		}
+	}
+}
+
+type postController struct {
+	Controller
+}
+
+func (c postController) GET(ctx *Context) {
+	ctx.Text("GET")
+}
+
+func (c postController) DELETE(ctx *Context) {
+	ctx.Text("DELETE")
+}
+
+func (c postController) Requirements() map[string]Requirement {
+	return map[string]Requirement{
+		"DELETE": {Permissions: []string{"posts.delete"}},
+	}
+}
+
+func TestAuthorizeMiddleware(t *testing.T) {
+	r := NewRouter()
+	r.SetPolicy(newTestRBAC())
+	r.AddMiddleware(testPrincipals)
+
+	var handled error
+	r.SetErrorHandler(func(ctx *Context, code int, err error) {
+		handled = err
+		ctx.Error(err.Error(), code)
+	})
+
+	ok := HandlerFunc(func(ctx *Context) {
+		ctx.Text("OK")
+	})
+	r.GET("/posts", Chain(ok, RequirePermissions("posts.read")))
+	admin := r.Group("/admin", RequireRoles("admin"))
+	admin.GET("/users", ok)
+	admin.Group("/editor", RequireRoles("editor")).GET("/posts", ok)
+
+	c := &postController{}
+	c.AddMiddleware(simpleMiddleware{})
+	r.RegisterController("/controller", c)
+
+	tests := []struct {
+		method string
+		path   string
+		user   string
+		code   int
+		err    error
+	}{
+		{"GET", "/posts", "guest", 200, nil},
+		{"GET", "/posts", "", 401, ErrUnauthenticated},
+		{"GET", "/posts", "unknown", 401, ErrUnauthenticated},
+		{"GET", "/admin/users", "admin", 200, nil},
+		{"GET", "/admin/users", "editor", 403, ErrForbidden},
+		// Both of the group and sub group's requirements are checked.
+		{"GET", "/admin/editor/posts", "admin", 403, ErrForbidden},
+		{"GET", "/controller", "", 200, nil},
+		{"DELETE", "/controller", "editor", 403, ErrForbidden},
+		{"DELETE", "/controller", "admin", 200, nil},
+	}
+	for _, test := range tests {
+		handled = nil
+		resp := serve(t, r.Handler, test.method+" "+test.path+" HTTP/1.1\r\nUser: "+test.user+"\r\n\r\n")
+		if resp.StatusCode() != test.code {
+			t.Errorf("%s %s %q: unexpected status code %d. Expected %d", test.method, test.path, test.user, resp.StatusCode(), test.code)
+		}
+		if handled != test.err {
+			t.Errorf("%s %s %q: expected error %v, got %v", test.method, test.path, test.user, test.err, handled)
+		}
+	}
+
+	// The policy errors are handled with status code 500.
+	failure := errors.New("policy failure")
+	r.SetPolicy(PolicyFunc(func(ctx *Context, principal interface{}, requirement Requirement) (bool, error) {
+		return false, failure
+	}))
+	if resp := serve(t, r.Handler, "GET /posts HTTP/1.1\r\nUser: guest\r\n\r\n"); resp.StatusCode() != 500 || handled != failure {
+		t.Errorf("unexpected status code %d and error %v", resp.StatusCode(), handled)
+	}
+
+	// No policy.
+	r.SetPolicy(nil)
+	if resp := serve(t, r.Handler, "GET /posts HTTP/1.1\r\nUser: guest\r\n\r\n"); resp.StatusCode() != 500 || handled != ErrNoPolicy {
+		t.Errorf("unexpected status code %d and error %v", resp.StatusCode(), handled)
+	}
+}
+
+func TestRouter_Group(t *testing.T) {
+	r := NewRouter()
+	api := r.Group("/api", simpleMiddleware{})
+	api.GET("/users", HandlerFunc(func(ctx *Context) {
+		ctx.Text("users")
+	}))
+	v2 := api.Group("/v2")
+	v2.POST("/users", HandlerFunc(func(ctx *Context) {
+		ctx.Text("v2")
+	}))
+
+	resp := serve(t, r.Handler, "GET /api/users HTTP/1.1\r\n\r\n")
+	if string(resp.Body()) != "users" || string(resp.Header.Peek("Middleware")) != "Simple" {
+		t.Errorf("unexpected response %q, %q", resp.Body(), resp.Header.Peek("Middleware"))
+	}
+	resp = serve(t, r.Handler, "POST /api/v2/users HTTP/1.1\r\n\r\n")
+	if string(resp.Body()) != "v2" || string(resp.Header.Peek("Middleware")) != "Simple" {
+		t.Errorf("unexpected response %q, %q", resp.Body(), resp.Header.Peek("Middleware"))
+	}
+
+	routes := r.Routes()
+	if len(routes) != 2 || routes[0] != (Route{Method: "GET", Path: "/api/users"}) || routes[1] != (Route{Method: "POST", Path: "/api/v2/users"}) {
+		t.Errorf("unexpected routes %v", routes)
+	}
+}
@@ -0,0 +1,472 @@
+package clevergo
+
+import (
+	"container/list"
+	"github.com/valyala/fasthttp"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a response stored by CacheMiddleware.
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Tags       []string  // Tags for purging.
+	Time       time.Time // When the response was generated.
+	Expires    time.Time // The response is fresh until Expires.
+	StaleUntil time.Time // The stale response can be served while revalidating until StaleUntil.
+}
+
+// CacheStore stores the cached responses, the custom stores, such as Redis, implement it.
+type CacheStore interface {
+	// Get returns the response of the key, the stale responses should be kept until StaleUntil.
+	Get(key string) (*CachedResponse, bool)
+	// Set stores the response of the key.
+	Set(key string, resp *CachedResponse)
+	// Delete removes the response of the key.
+	Delete(key string)
+	// DeleteTag removes the responses which have the tag.
+	DeleteTag(tag string)
+}
+
+// LRUCacheStore is an in-memory CacheStore, which evicts the least recently used responses
+// if the capacity is exceeded.
+type LRUCacheStore struct {
+	mu       sync.Mutex
+	capacity int
+	list     *list.List                          // Entries, the front is the most recently used.
+	entries  map[string]*list.Element            // Entries by key.
+	tags     map[string]map[string]*list.Element // Entries by tag.
+	now      func() time.Time
+}
+
+type lruCacheEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewLRUCacheStore returns a LRUCacheStore's instance, which keeps up to capacity responses.
+func NewLRUCacheStore(capacity int) *LRUCacheStore {
+	return &LRUCacheStore{
+		capacity: capacity,
+		list:     list.New(),
+		entries:  make(map[string]*list.Element),
+		tags:     make(map[string]map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Get implemented CacheStore Interface.
+func (s *LRUCacheStore) Get(key string) (*CachedResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	resp := elem.Value.(*lruCacheEntry).resp
+	if !s.now().Before(resp.StaleUntil) {
+		s.remove(elem)
+		return nil, false
+	}
+	s.list.MoveToFront(elem)
+	return resp, true
+}
+
+// Set implemented CacheStore Interface.
+func (s *LRUCacheStore) Set(key string, resp *CachedResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+	elem := s.list.PushFront(&lruCacheEntry{key: key, resp: resp})
+	s.entries[key] = elem
+	for _, tag := range resp.Tags {
+		if s.tags[tag] == nil {
+			s.tags[tag] = make(map[string]*list.Element)
+		}
+		s.tags[tag][key] = elem
+	}
+	for s.capacity > 0 && s.list.Len() > s.capacity {
+		s.remove(s.list.Back())
+	}
+}
+
+// Delete implemented CacheStore Interface.
+func (s *LRUCacheStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+}
+
+// DeleteTag implemented CacheStore Interface.
+func (s *LRUCacheStore) DeleteTag(tag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, elem := range s.tags[tag] {
+		s.remove(elem)
+	}
+}
+
+// Len returns the count of responses.
+func (s *LRUCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list.Len()
+}
+
+func (s *LRUCacheStore) remove(elem *list.Element) {
+	entry := s.list.Remove(elem).(*lruCacheEntry)
+	delete(s.entries, entry.key)
+	for _, tag := range entry.resp.Tags {
+		delete(s.tags[tag], entry.key)
+		if len(s.tags[tag]) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
+
+// CacheKey returns the cache key of the request, the query parameters are sorted,
+// so that the same parameters in different order share the key.
+func CacheKey(method, host, uri string) string {
+	path, query := uri, ""
+	if i := strings.IndexByte(uri, '?'); i >= 0 {
+		path, query = uri[:i], uri[i+1:]
+	}
+	key := method + " " + strings.ToLower(host) + path
+	if query != "" {
+		params := strings.Split(query, "&")
+		sort.Strings(params)
+		key += "?" + strings.Join(params, "&")
+	}
+	return key
+}
+
+// CacheConfig for CacheMiddleware.
+type CacheConfig struct {
+	Store CacheStore
+	// TTL is the freshness lifetime of the responses without max-age or s-maxage.
+	TTL time.Duration
+	// StaleWhileRevalidate is the period in which the stale responses are served while being revalidated
+	// in background, the stale-while-revalidate directive of the response takes precedence.
+	StaleWhileRevalidate time.Duration
+	// Vary is the request headers which select the variant of response, such as "Accept-Encoding".
+	Vary []string
+	// KeyFunc returns the cache key of request, defaults to CacheKey of the method, host and request URI.
+	KeyFunc func(ctx *Context) string
+}
+
+// NewCacheConfig returns default cache configuration, which caches the responses
+// without max-age for a minute, and varies on Accept-Encoding.
+func NewCacheConfig(store CacheStore) *CacheConfig {
+	return &CacheConfig{
+		Store:   store,
+		TTL:     time.Minute,
+		Vary:    []string{"Accept-Encoding"},
+		KeyFunc: defaultCacheKey,
+	}
+}
+
+func defaultCacheKey(ctx *Context) string {
+	return CacheKey(string(ctx.Method()), ctx.RealHost(), string(ctx.RequestURI()))
+}
+
+// CacheMiddleware caches the successful GET and HEAD responses in the store.
+//
+// The Cache-Control headers are honoured: the requests with no-store bypass the cache, and the requests with
+// no-cache or max-age=0 are regenerated; the responses with no-store, no-cache, private, Set-Cookie or Vary: *
+// are not stored, and s-maxage and max-age take precedence over CacheConfig.TTL. The requests with
+// Authorization header are not cached, neither the personalized responses, which contain the CSRF token,
+// the CSP nonce or the session.
+//
+// The concurrent requests of a missing key are coalesced, only one of them is handled and the others share
+// its response. The X-Cache header of responses is HIT, STALE or MISS.
+type CacheMiddleware struct {
+	config *CacheConfig
+	now    func() time.Time
+
+	mu    sync.Mutex
+	calls map[string]*cacheCall // In-flight handling of keys.
+}
+
+// cacheCall is an in-flight handling, resp is nil if the response is not cacheable.
+type cacheCall struct {
+	done chan struct{}
+	resp *CachedResponse
+}
+
+// NewCacheMiddleware returns a CacheMiddleware's instance.
+func NewCacheMiddleware(config *CacheConfig) *CacheMiddleware {
+	return &CacheMiddleware{
+		config: config,
+		now:    time.Now,
+		calls:  make(map[string]*cacheCall),
+	}
+}
+
+// cacheKeyTag is the implicit tag of the variants of a key.
+const cacheKeyTag = "\x00key:"
+
+// PurgeKey removes the cached responses of the key, including all variants of the Vary headers.
+func (m *CacheMiddleware) PurgeKey(key string) {
+	m.config.Store.DeleteTag(cacheKeyTag + key)
+}
+
+// PurgeTag removes the cached responses which have the tag, see Context.AddCacheTags.
+func (m *CacheMiddleware) PurgeTag(tag string) {
+	m.config.Store.DeleteTag(tag)
+}
+
+// AddCacheTags adds the tags to the response, so that it can be purged by CacheMiddleware.PurgeTag.
+func (ctx *Context) AddCacheTags(tags ...string) {
+	ctx.checkReleased()
+	ctx.cacheTags = append(ctx.cacheTags, tags...)
+}
+
+// Handle implemented Middleware Interface.
+func (m *CacheMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		if !ctx.IsGet() && !ctx.IsHead() || len(ctx.Request.Header.Peek("Authorization")) > 0 || isPersonalized(ctx) {
+			next.Handle(ctx)
+			return
+		}
+		cc := parseCacheControl(ctx.Request.Header.Peek("Cache-Control"))
+		if cc.noStore {
+			next.Handle(ctx)
+			return
+		}
+
+		keyFunc := m.config.KeyFunc
+		if keyFunc == nil {
+			keyFunc = defaultCacheKey
+		}
+		baseKey := keyFunc(ctx)
+		key := baseKey
+		for _, name := range m.config.Vary {
+			key += "\n" + name + ": " + string(ctx.Request.Header.Peek(name))
+		}
+
+		if !cc.noCache && cc.maxAge != 0 {
+			if resp, ok := m.config.Store.Get(key); ok {
+				now := m.now()
+				if now.Before(resp.Expires) {
+					m.write(ctx, resp, "HIT")
+					return
+				}
+				if now.Before(resp.StaleUntil) {
+					m.revalidate(ctx, next, key, baseKey)
+					m.write(ctx, resp, "STALE")
+					return
+				}
+			}
+		}
+
+		call, leader := m.join(key)
+		if !leader {
+			select {
+			case <-call.done:
+				if call.resp != nil {
+					m.write(ctx, call.resp, "HIT")
+					return
+				}
+			case <-ctx.Context().Done():
+			}
+			next.Handle(ctx)
+			return
+		}
+
+		defer m.leave(key, call)
+		header := responseHeader(ctx)
+		next.Handle(ctx)
+		call.resp = m.store(ctx, key, baseKey, header)
+		ctx.Response.Header.Set("X-Cache", "MISS")
+	})
+}
+
+// join returns the in-flight handling of the key, leader is true if the caller should handle the request.
+func (m *CacheMiddleware) join(key string) (call *cacheCall, leader bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if call, ok := m.calls[key]; ok {
+		return call, false
+	}
+	call = &cacheCall{done: make(chan struct{})}
+	m.calls[key] = call
+	return call, true
+}
+
+func (m *CacheMiddleware) leave(key string, call *cacheCall) {
+	m.mu.Lock()
+	delete(m.calls, key)
+	m.mu.Unlock()
+	close(call.done)
+}
+
+// revalidate regenerates the response in background by a copy of the context,
+// it does nothing if the key is being handled.
+func (m *CacheMiddleware) revalidate(ctx *Context, next Handler, key, baseKey string) {
+	call, leader := m.join(key)
+	if !leader {
+		return
+	}
+	c := ctx.Copy()
+	go func() {
+		defer m.leave(key, call)
+		defer func() {
+			if err := recover(); err != nil {
+				c.Logger().Printf("Cache: failed to revalidate %q: %v", key, err)
+			}
+		}()
+		next.Handle(c)
+		call.resp = m.store(c, key, baseKey, nil)
+	}()
+}
+
+// isPersonalized reports whether the response contains the per-user or per-request content,
+// such as the CSRF token, the CSP nonce and the session values, which must not be replayed to other clients.
+func isPersonalized(ctx *Context) bool {
+	return ctx.csrfToken != "" || ctx.cspNonce != "" || ctx.Session != nil
+}
+
+// responseHeader returns a copy of the response headers.
+func responseHeader(ctx *Context) http.Header {
+	header := make(http.Header)
+	ctx.Response.Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+	return header
+}
+
+// store stores the response if it is cacheable, returns nil otherwise.
+//
+// Only the headers produced by the handler are stored, the headers which are unchanged since before
+// handling are skipped, since they were set by the outer middlewares on each request.
+func (m *CacheMiddleware) store(ctx *Context, key, baseKey string, before http.Header) *CachedResponse {
+	response := &ctx.Response
+	if response.StatusCode() != fasthttp.StatusOK || response.IsBodyStream() || isPersonalized(ctx) {
+		return nil
+	}
+	cc := parseCacheControl(response.Header.Peek("Cache-Control"))
+	if cc.noStore || cc.noCache || cc.private || strings.Contains(string(response.Header.Peek("Vary")), "*") {
+		return nil
+	}
+	hasCookie := false
+	response.Header.VisitAllCookie(func(key, value []byte) {
+		hasCookie = true
+	})
+	if hasCookie {
+		return nil
+	}
+
+	ttl := m.config.TTL
+	if cc.sMaxAge >= 0 {
+		ttl = time.Duration(cc.sMaxAge) * time.Second
+	} else if cc.maxAge >= 0 {
+		ttl = time.Duration(cc.maxAge) * time.Second
+	}
+	if ttl <= 0 {
+		return nil
+	}
+	swr := m.config.StaleWhileRevalidate
+	if cc.staleWhileRevalidate >= 0 {
+		swr = time.Duration(cc.staleWhileRevalidate) * time.Second
+	}
+
+	now := m.now()
+	resp := &CachedResponse{
+		StatusCode: response.StatusCode(),
+		Header:     make(http.Header),
+		Body:       append([]byte(nil), response.Body()...),
+		Tags:       append([]string{cacheKeyTag + baseKey}, ctx.cacheTags...),
+		Time:       now,
+		Expires:    now.Add(ttl),
+		StaleUntil: now.Add(ttl + swr),
+	}
+	for k, values := range responseHeader(ctx) {
+		switch k {
+		case "Content-Length", "Date", "Connection", "Set-Cookie", "X-Cache":
+		default:
+			if !reflect.DeepEqual(values, before[k]) {
+				resp.Header[k] = values
+			}
+		}
+	}
+	m.config.Store.Set(key, resp)
+	return resp
+}
+
+// write responses the cached response, or 304 if the client's copy is fresh.
+func (m *CacheMiddleware) write(ctx *Context, resp *CachedResponse, status string) {
+	ctx.SetStatusCode(resp.StatusCode)
+	for k, values := range resp.Header {
+		// Replace the headers which were set before handling, such as by the outer middlewares.
+		for i, v := range values {
+			if i == 0 {
+				ctx.Response.Header.Set(k, v)
+			} else {
+				ctx.Response.Header.Add(k, v)
+			}
+		}
+	}
+	age := m.now().Sub(resp.Time) / time.Second
+	ctx.Response.Header.Set("Age", strconv.FormatInt(int64(age), 10))
+	ctx.Response.Header.Set("X-Cache", status)
+
+	modtime, _ := parseHTTPDate(ctx.Response.Header.Peek("Last-Modified"))
+	if checkPreconditions(ctx, string(ctx.Response.Header.Peek("ETag")), modtime) == fasthttp.StatusNotModified {
+		writeNotModified(ctx)
+		return
+	}
+	ctx.Response.SetBody(resp.Body)
+}
+
+// cacheControl contains the directives of Cache-Control header, the negative durations mean absence.
+type cacheControl struct {
+	noStore              bool
+	noCache              bool
+	private              bool
+	maxAge               int
+	sMaxAge              int
+	staleWhileRevalidate int
+}
+
+func parseCacheControl(value []byte) cacheControl {
+	cc := cacheControl{maxAge: -1, sMaxAge: -1, staleWhileRevalidate: -1}
+	for _, directive := range strings.Split(string(value), ",") {
+		name, arg := strings.TrimSpace(directive), ""
+		if i := strings.IndexByte(name, '='); i >= 0 {
+			name, arg = strings.TrimSpace(name[:i]), strings.Trim(strings.TrimSpace(name[i+1:]), `"`)
+		}
+		seconds := func() int {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 {
+				return 0
+			}
+			return n
+		}
+		switch strings.ToLower(name) {
+		case "no-store":
+			cc.noStore = true
+		case "no-cache":
+			cc.noCache = true
+		case "private":
+			cc.private = true
+		case "max-age":
+			cc.maxAge = seconds()
+		case "s-maxage":
+			cc.sMaxAge = seconds()
+		case "stale-while-revalidate":
+			cc.staleWhileRevalidate = seconds()
+		}
+	}
+	return cc
+}
@@ -0,0 +1,256 @@
+package clevergo
+
+import (
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheMiddleware(t *testing.T) {
+	store := NewLRUCacheStore(100)
+	m := NewCacheMiddleware(NewCacheConfig(store))
+	var calls int32
+	r := NewRouter()
+	r.AddMiddleware(m)
+	handler := HandlerFunc(func(ctx *Context) {
+		n := atomic.AddInt32(&calls, 1)
+		ctx.AddCacheTags("posts")
+		switch string(ctx.Path()) {
+		case "/private":
+			ctx.Response.Header.Set("Cache-Control", "private")
+		case "/cookie":
+			ctx.SetCookie("foo", "bar", nil)
+		case "/etag":
+			ctx.Response.Header.Set("ETag", `"v1"`)
+		}
+		ctx.Text(fmt.Sprintf("%d %s", n, ctx.Request.Header.Peek("Accept-Encoding")))
+	})
+	for _, path := range []string{"/posts", "/private", "/cookie", "/etag"} {
+		r.GET(path, handler)
+	}
+
+	tests := []struct {
+		request string
+		body    string
+		xCache  string
+	}{
+		{"GET /posts?a=1&b=2 HTTP/1.1\r\nHost: example.com\r\n\r\n", "1 ", "MISS"},
+		{"GET /posts?b=2&a=1 HTTP/1.1\r\nHost: EXAMPLE.com\r\n\r\n", "1 ", "HIT"},
+		{"GET /posts?a=1&b=2 HTTP/1.1\r\nHost: example.com\r\nAccept-Encoding: gzip\r\n\r\n", "2 gzip", "MISS"},
+		{"GET /posts?a=1&b=2 HTTP/1.1\r\nHost: example.com\r\nCache-Control: no-store\r\n\r\n", "3 ", ""},
+		{"GET /posts?a=1&b=2 HTTP/1.1\r\nHost: example.com\r\nCache-Control: no-cache\r\n\r\n", "4 ", "MISS"},
+		{"GET /posts?a=1&b=2 HTTP/1.1\r\nHost: example.com\r\n\r\n", "4 ", "HIT"},
+		{"GET /posts?a=1&b=2 HTTP/1.1\r\nHost: example.com\r\nAuthorization: Bearer foo\r\n\r\n", "5 ", ""},
+		{"GET /private HTTP/1.1\r\nHost: example.com\r\n\r\n", "6 ", "MISS"},
+		{"GET /private HTTP/1.1\r\nHost: example.com\r\n\r\n", "7 ", "MISS"},
+		{"GET /cookie HTTP/1.1\r\nHost: example.com\r\n\r\n", "8 ", "MISS"},
+		{"GET /cookie HTTP/1.1\r\nHost: example.com\r\n\r\n", "9 ", "MISS"},
+		{"GET /etag HTTP/1.1\r\nHost: example.com\r\n\r\n", "10 ", "MISS"},
+		{"GET /etag HTTP/1.1\r\nHost: example.com\r\nIf-None-Match: \"v1\"\r\n\r\n", "", "HIT"},
+	}
+	for i, test := range tests {
+		resp := serve(t, r.Handler, test.request)
+		if string(resp.Body()) != test.body || string(resp.Header.Peek("X-Cache")) != test.xCache {
+			t.Errorf("%d: unexpected response %q, X-Cache %q, expect %q, %q", i, resp.Body(), resp.Header.Peek("X-Cache"), test.body, test.xCache)
+		}
+	}
+
+	resp := serve(t, r.Handler, "GET /posts?a=1&b=2 HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	if string(resp.Header.Peek("Age")) != "0" || string(resp.Header.ContentType()) != "text/plain; charset=utf-8" {
+		t.Errorf("unexpected headers %s", resp.Header.String())
+	}
+
+	// Purge all variants of the key.
+	m.PurgeKey(CacheKey("GET", "example.com", "/posts?a=1&b=2"))
+	if store.Len() != 1 {
+		t.Errorf("unexpected count of responses %d", store.Len())
+	}
+	m.PurgeTag("posts")
+	if store.Len() != 0 {
+		t.Errorf("unexpected count of responses %d", store.Len())
+	}
+}
+
+func TestCacheMiddleware_StaleWhileRevalidate(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	store := NewLRUCacheStore(10)
+	store.now = clock
+	config := NewCacheConfig(store)
+	config.StaleWhileRevalidate = time.Minute
+	m := NewCacheMiddleware(config)
+	m.now = clock
+
+	var calls int32
+	r := NewRouter()
+	r.AddMiddleware(m)
+	r.GET("/", HandlerFunc(func(ctx *Context) {
+		ctx.Response.Header.Set("Cache-Control", "max-age=10")
+		ctx.Text(fmt.Sprint(atomic.AddInt32(&calls, 1)))
+	}))
+
+	serve(t, r.Handler, "GET / HTTP/1.1\r\n\r\n")
+	mu.Lock()
+	now = now.Add(30 * time.Second)
+	mu.Unlock()
+	resp := serve(t, r.Handler, "GET / HTTP/1.1\r\n\r\n")
+	if string(resp.Body()) != "1" || string(resp.Header.Peek("X-Cache")) != "STALE" || string(resp.Header.Peek("Age")) != "30" {
+		t.Errorf("unexpected response %q, headers %s", resp.Body(), resp.Header.String())
+	}
+
+	// Wait for the revalidation.
+	for i := 0; i < 100; i++ {
+		if resp, ok := store.Get(CacheKey("GET", "", "/") + "\nAccept-Encoding: "); ok && string(resp.Body) == "2" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	resp = serve(t, r.Handler, "GET / HTTP/1.1\r\n\r\n")
+	if string(resp.Body()) != "2" || string(resp.Header.Peek("X-Cache")) != "HIT" {
+		t.Errorf("unexpected response %q, headers %s", resp.Body(), resp.Header.String())
+	}
+
+	// Too stale.
+	mu.Lock()
+	now = now.Add(time.Hour)
+	mu.Unlock()
+	resp = serve(t, r.Handler, "GET / HTTP/1.1\r\n\r\n")
+	if string(resp.Body()) != "3" || string(resp.Header.Peek("X-Cache")) != "MISS" {
+		t.Errorf("unexpected response %q, headers %s", resp.Body(), resp.Header.String())
+	}
+}
+
+func TestCacheMiddleware_Coalescing(t *testing.T) {
+	var calls int32
+	entered, release := make(chan struct{}), make(chan struct{})
+	r := NewRouter()
+	r.AddMiddleware(NewCacheMiddleware(NewCacheConfig(NewLRUCacheStore(10))))
+	r.GET("/slow", HandlerFunc(func(ctx *Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(entered)
+		}
+		<-release
+		ctx.Text("slow")
+	}))
+
+	request := func() string {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI("/slow")
+		r.Handler(ctx)
+		return string(ctx.Response.Body())
+	}
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 5)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		bodies[0] = request()
+	}()
+	<-entered
+	for i := 1; i < len(bodies); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i] = request()
+		}(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("unexpected calls %d", calls)
+	}
+	for _, body := range bodies {
+		if body != "slow" {
+			t.Errorf("unexpected bodies %q", bodies)
+			break
+		}
+	}
+}
+
+func TestLRUCacheStore(t *testing.T) {
+	store := NewLRUCacheStore(2)
+	expires := time.Now().Add(time.Hour)
+	for _, key := range []string{"a", "b"} {
+		store.Set(key, &CachedResponse{Tags: []string{"t"}, StaleUntil: expires})
+	}
+	store.Get("a")
+	store.Set("c", &CachedResponse{StaleUntil: expires})
+	if _, ok := store.Get("b"); ok || store.Len() != 2 {
+		t.Errorf("the least recently used response should be evicted")
+	}
+	store.DeleteTag("t")
+	if _, ok := store.Get("a"); ok || store.Len() != 1 {
+		t.Errorf("the responses of tag should be deleted")
+	}
+	store.Delete("c")
+	if store.Len() != 0 || len(store.tags) != 0 {
+		t.Errorf("unexpected store %d, %v", store.Len(), store.tags)
+	}
+}
+
+func TestCacheMiddleware_Personalized(t *testing.T) {
+	var calls int32
+	handler := HandlerFunc(func(ctx *Context) {
+		atomic.AddInt32(&calls, 1)
+		ctx.Text(ctx.CSRFToken() + " " + ctx.CSPNonce())
+	})
+	csrfConfig := NewCSRFConfig()
+	csrfConfig.DoubleSubmit = true
+	secureConfig := NewSecureConfig()
+	secureConfig.ContentSecurityPolicy = "script-src 'nonce-{nonce}'"
+
+	tests := [][]Middleware{
+		{NewCSRFMiddleware(csrfConfig), NewSecureMiddleware(secureConfig), NewCacheMiddleware(NewCacheConfig(NewLRUCacheStore(100)))},
+		// The cache is outermost, the personalized responses must not be stored.
+		{NewCacheMiddleware(NewCacheConfig(NewLRUCacheStore(100))), NewCSRFMiddleware(csrfConfig), NewSecureMiddleware(secureConfig)},
+		{NewCacheMiddleware(NewCacheConfig(NewLRUCacheStore(100))), NewCSRFMiddleware(nil)},
+	}
+	for i, middlewares := range tests {
+		r := NewRouter()
+		r.SetSessionStore(newTestSessionStore())
+		r.GET("/", Chain(handler, middlewares...))
+		atomic.StoreInt32(&calls, 0)
+		bodies := map[string]bool{}
+		for j := 0; j < 2; j++ {
+			resp := serve(t, r.Handler, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+			if xCache := string(resp.Header.Peek("X-Cache")); xCache == "HIT" {
+				t.Errorf("%d: the personalized response was served from cache", i)
+			}
+			bodies[string(resp.Body())] = true
+		}
+		if calls != 2 || len(bodies) != 2 {
+			t.Errorf("%d: unexpected calls %d, bodies %v", i, calls, bodies)
+		}
+	}
+}
+
+func TestCacheMiddleware_ReplaceHeaders(t *testing.T) {
+	r := NewRouter()
+	r.AddMiddleware(simpleMiddleware{})
+	r.AddMiddleware(NewCacheMiddleware(NewCacheConfig(NewLRUCacheStore(100))))
+	r.GET("/", HandlerFunc(func(ctx *Context) {
+		ctx.Response.Header.Set("X-Foo", "bar")
+		ctx.Text("foo")
+	}))
+
+	for _, xCache := range []string{"MISS", "HIT"} {
+		resp := serve(t, r.Handler, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+		if string(resp.Header.Peek("X-Cache")) != xCache {
+			t.Errorf("unexpected X-Cache %q, expect %q", resp.Header.Peek("X-Cache"), xCache)
+		}
+		if values := resp.Header.PeekAll("Middleware"); len(values) != 1 || string(resp.Header.Peek("X-Foo")) != "bar" {
+			t.Errorf("%s: unexpected headers %s", xCache, resp.Header.String())
+		}
+	}
+}
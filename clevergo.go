@@ -48,3 +48,12 @@ type HandlerFunc func(*Context)
 func (f HandlerFunc) Handle(ctx *Context) {
 	f(ctx)
 }
+
+// Chain returns the handler wrapped by the middlewares, the first middleware is the outermost,
+// so that the middlewares can be applied to a single route.
+func Chain(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i].Handle(handler)
+	}
+	return handler
+}
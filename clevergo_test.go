@@ -7,8 +7,11 @@ import (
 	"encoding/xml"
 	"fmt"
 	"github.com/valyala/fasthttp"
+	"html/template"
 	"net"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -622,6 +625,69 @@ func (m simpleMiddleware) Handle(next Handler) Handler {
 	})
 }
 
+func TestTemplateMasters(t *testing.T) {
+	var tm templateMasters
+	tpl := template.Must(template.New("page").Parse("page"))
+	master, err := tm.get(tpl)
+	if err != nil || master == tpl {
+		t.Fatalf("Unexpected master %v, error %v", master, err)
+	}
+	if m, _ := tm.get(tpl); m != master {
+		t.Errorf("The master should be reused")
+	}
+
+	// The templates parsed per request don't leak, and the recently used one is kept.
+	for i := 0; i < maxTemplateMasters*2; i++ {
+		if _, err := tm.get(template.Must(template.New("page").Parse("page"))); err != nil {
+			t.Fatal(err)
+		}
+		if i%100 == 0 {
+			tm.get(tpl)
+		}
+	}
+	if len(tm.masters) != maxTemplateMasters || tm.list.Len() != maxTemplateMasters {
+		t.Errorf("Unexpected number of masters %d", len(tm.masters))
+	}
+	if m, _ := tm.get(tpl); m != master {
+		t.Errorf("The recently used master should not be evicted")
+	}
+
+	executed := template.Must(template.New("page").Parse("page"))
+	executed.Execute(&bytes.Buffer{}, nil)
+	if _, err := tm.get(executed); err == nil {
+		t.Errorf("Expected error of the executed template")
+	}
+}
+
+func TestContext_RenderEvicted(t *testing.T) {
+	tpl := template.Must(template.New("page").Funcs(TemplateFuncs()).Parse(`{{ cspNonce }}`))
+	r := NewRouter()
+	r.GET("/", HandlerFunc(func(ctx *Context) {
+		ctx.SetTemplateFunc("cspNonce", func() string {
+			return "nonce"
+		})
+		ctx.Render(tpl, nil)
+	}))
+	r.GET("/executed", HandlerFunc(func(ctx *Context) {
+		executed := template.Must(template.New("page").Parse("page"))
+		executed.Execute(&bytes.Buffer{}, nil)
+		ctx.Render(executed, nil)
+	}))
+
+	for i := 0; i < 2; i++ {
+		resp := serve(t, r.Handler, "GET / HTTP/1.1\r\n\r\n")
+		if string(resp.Body()) != "nonce" {
+			t.Errorf("%d: unexpected body %q", i, resp.Body())
+		}
+		// The original template is never executed, so that it can be cloned again after eviction.
+		r.templates = templateMasters{}
+	}
+
+	if resp := serve(t, r.Handler, "GET /executed HTTP/1.1\r\n\r\n"); resp.StatusCode() != 500 {
+		t.Errorf("Unexpected status code %d", resp.StatusCode())
+	}
+}
+
 func TestController(t *testing.T) {
 	app := NewApplication()
 	r := NewRouter()
@@ -688,6 +754,64 @@ func TestController(t *testing.T) {
 	}
 }
 
+// wrappingController overrides Handle, and implements GET only.
+type wrappingController struct {
+	Controller
+}
+
+func (c wrappingController) Handle(next Handler) Handler {
+	next = c.Controller.Handle(next)
+	return HandlerFunc(func(ctx *Context) {
+		ctx.Response.Header.Add("Controller", "Handle")
+		next.Handle(ctx)
+	})
+}
+
+func (c wrappingController) GET(ctx *Context) {
+	ctx.Text("GET")
+}
+
+// plainController doesn't embed Controller.
+type plainController struct{}
+
+func (c plainController) Handle(next Handler) Handler {
+	return next
+}
+
+func (c plainController) POST(ctx *Context) {
+	ctx.Text("POST")
+}
+
+func TestRouter_RegisterController(t *testing.T) {
+	r := NewRouter()
+	c := &wrappingController{}
+	c.AddMiddleware(simpleMiddleware{})
+	r.RegisterController("/wrapping", c)
+	r.RegisterController("/plain", plainController{})
+
+	resp := serve(t, r.Handler, "GET /wrapping HTTP/1.1\r\n\r\n")
+	if string(resp.Body()) != "GET" {
+		t.Errorf("Unexpected body %q", resp.Body())
+	}
+	// The controller's Handle and middlewares wrap the handler once.
+	if n := len(resp.Header.PeekAll("Controller")); n != 1 {
+		t.Errorf("Controller.Handle is applied %d times", n)
+	}
+	if n := len(resp.Header.PeekAll("Middleware")); n != 1 {
+		t.Errorf("The middleware is applied %d times", n)
+	}
+
+	resp = serve(t, r.Handler, "POST /plain HTTP/1.1\r\n\r\n")
+	if string(resp.Body()) != "POST" {
+		t.Errorf("Unexpected body %q", resp.Body())
+	}
+
+	expected := []Route{{Method: "GET", Path: "/wrapping"}, {Method: "POST", Path: "/plain"}}
+	if routes := r.Routes(); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("Unexpected routes %v", routes)
+	}
+}
+
 type readWriter struct {
 	net.Conn
 	r bytes.Buffer
@@ -725,3 +849,149 @@ func (rw *readWriter) SetReadDeadline(t time.Time) error {
 func (rw *readWriter) SetWriteDeadline(t time.Time) error {
 	return nil
 }
+
+// serve sends the raw request to the handler and returns the response.
+func serve(t *testing.T, handler fasthttp.RequestHandler, request string) *fasthttp.Response {
+	s := &fasthttp.Server{
+		Handler: handler,
+	}
+
+	rw := &readWriter{}
+	rw.r.WriteString(request)
+
+	ch := make(chan error)
+	go func() {
+		ch <- s.ServeConn(rw)
+	}()
+
+	select {
+	case err := <-ch:
+		if err != nil {
+			t.Fatalf("return error %s", err)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatalf("timeout")
+	}
+
+	resp := &fasthttp.Response{}
+	if strings.HasPrefix(request, "HEAD ") {
+		resp.SkipBody = true
+	}
+	if err := resp.Read(bufio.NewReader(&rw.w)); err != nil {
+		t.Fatalf("Unexpected error when reading response: %s", err)
+	}
+	return resp
+}
+
+func TestRouter_AllowedMethods(t *testing.T) {
+	r := NewRouter()
+	handler := HandlerFunc(func(ctx *Context) {})
+	r.GET("/users", handler)
+	r.POST("/users", handler)
+	r.DELETE("/users/:id", handler)
+	r.Handle("PURGE", "/users/:id", handler)
+	r.GET("/static/*filepath", handler)
+
+	tests := []struct {
+		path    string
+		methods string
+	}{
+		{"/users", "GET, HEAD, POST, OPTIONS"},
+		{"/users/1", "DELETE, OPTIONS, PURGE"},
+		{"/users/", ""},
+		{"/static/css/app.css", "GET, HEAD, OPTIONS"},
+		{"/static", ""},
+		{"/none", ""},
+	}
+	for _, test := range tests {
+		if methods := strings.Join(r.AllowedMethods(test.path), ", "); methods != test.methods {
+			t.Errorf("AllowedMethods(%q) = %q, expect %q", test.path, methods, test.methods)
+		}
+	}
+
+	if routes := r.Routes(); len(routes) != 5 || routes[0] != (Route{Method: "GET", Path: "/users"}) {
+		t.Errorf("Unexpected routes %v", routes)
+	}
+
+	// Automatic OPTIONS handler.
+	r.AddMiddleware(simpleMiddleware{})
+	resp := serve(t, r.Handler, "OPTIONS /users HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 204 {
+		t.Errorf("Unexpected status code %d. Expected %d", resp.StatusCode(), 204)
+	}
+	if allow := string(resp.Header.Peek("Allow")); allow != "GET, HEAD, POST, OPTIONS" {
+		t.Errorf("Unexpected Allow %q", allow)
+	}
+	if !bytes.Equal(resp.Header.Peek("Middleware"), []byte("Simple")) {
+		t.Errorf("Automatic OPTIONS handler should be wrapped by middlewares")
+	}
+}
+
+func TestRouter_PanicHandler(t *testing.T) {
+	r := NewRouter()
+	r.Router.PanicHandler = func(ctx *fasthttp.RequestCtx, rcv interface{}) {
+		ctx.Error(fmt.Sprint(rcv), fasthttp.StatusInternalServerError)
+	}
+	r.GET("/", HandlerFunc(func(ctx *Context) {
+		panic("foo")
+	}))
+
+	resp := serve(t, r.Handler, "GET / HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 500 || string(resp.Body()) != "foo" {
+		t.Errorf("Unexpected response %d %q", resp.StatusCode(), resp.Body())
+	}
+}
+
+func TestRouter_MethodNotAllowed(t *testing.T) {
+	r := NewRouter()
+	var handled error
+	r.SetErrorHandler(func(ctx *Context, code int, err error) {
+		handled = err
+		ctx.Error(fasthttp.StatusMessage(code), code)
+	})
+	r.RegisterController("/users", &wrappingController{})
+	r.GET("/users/:id<int>", HandlerFunc(func(ctx *Context) {}))
+	r.GET("/posts/:id<int>", HandlerFunc(func(ctx *Context) {}))
+	r.POST("/posts/:slug", HandlerFunc(func(ctx *Context) {}))
+
+	tests := []struct {
+		request string
+		code    int
+		allow   string
+	}{
+		{"POST /users HTTP/1.1\r\n\r\n", 405, "GET, HEAD, OPTIONS"},
+		{"DELETE /users/1 HTTP/1.1\r\n\r\n", 405, "GET, HEAD, OPTIONS"},
+		// The constraint is not satisfied.
+		{"DELETE /users/abc HTTP/1.1\r\n\r\n", 404, ""},
+		{"POST /none HTTP/1.1\r\n\r\n", 404, ""},
+		{"GET /posts/1 HTTP/1.1\r\n\r\n", 200, ""},
+		// The route of tree doesn't satisfy the constraint, falls back to the other methods.
+		{"GET /posts/abc HTTP/1.1\r\n\r\n", 405, "POST, OPTIONS"},
+		{"HEAD /posts/abc HTTP/1.1\r\n\r\n", 405, "POST, OPTIONS"},
+	}
+	for _, test := range tests {
+		handled = nil
+		resp := serve(t, r.Handler, test.request)
+		if resp.StatusCode() != test.code {
+			t.Errorf("%q: unexpected status code %d. Expected %d", test.request, resp.StatusCode(), test.code)
+		}
+		if allow := string(resp.Header.Peek("Allow")); allow != test.allow {
+			t.Errorf("%q: unexpected Allow %q. Expected %q", test.request, allow, test.allow)
+		}
+		if test.code == 405 && handled != ErrMethodNotAllowed {
+			t.Errorf("%q: unexpected error %v", test.request, handled)
+		}
+	}
+
+	// Automatic HEAD handler.
+	resp := serve(t, r.Handler, "HEAD /users HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 200 {
+		t.Errorf("Unexpected status code %d. Expected %d", resp.StatusCode(), 200)
+	}
+	if len(resp.Body()) != 0 || resp.Header.ContentLength() != len("GET") {
+		t.Errorf("Unexpected body %q, Content-Length %d", resp.Body(), resp.Header.ContentLength())
+	}
+	if !bytes.Equal(resp.Header.Peek("Controller"), []byte("Handle")) {
+		t.Errorf("Automatic HEAD handler should be wrapped by the controller")
+	}
+}
@@ -0,0 +1,203 @@
+package clevergo
+
+import (
+	"bytes"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EncodingBrotli brotli encoding.
+	EncodingBrotli = "br"
+	// EncodingGzip gzip encoding.
+	EncodingGzip = "gzip"
+	// EncodingDeflate deflate encoding.
+	EncodingDeflate = "deflate"
+)
+
+// CompressConfig for CompressMiddleware.
+type CompressConfig struct {
+	Encodings    []string // Supported encodings in order of preference.
+	MinLength    int      // Minimum length of body to be compressed.
+	ContentTypes []string // Prefixes of Content-Type to be compressed, empty means all types.
+	BrotliLevel  int      // Brotli compression level.
+	GzipLevel    int      // Gzip compression level.
+	DeflateLevel int      // Deflate compression level.
+}
+
+// NewCompressConfig returns default compression configuration.
+func NewCompressConfig() *CompressConfig {
+	return &CompressConfig{
+		Encodings: []string{EncodingBrotli, EncodingGzip, EncodingDeflate},
+		MinLength: 1024,
+		ContentTypes: []string{
+			"text/",
+			"application/json",
+			"application/javascript",
+			"application/xml",
+			"application/xhtml+xml",
+			"application/rss+xml",
+			"application/atom+xml",
+			"image/svg+xml",
+		},
+		BrotliLevel:  fasthttp.CompressBrotliDefaultCompression,
+		GzipLevel:    fasthttp.CompressDefaultCompression,
+		DeflateLevel: fasthttp.CompressDefaultCompression,
+	}
+}
+
+// CompressMiddleware compresses the response body according to the Accept-Encoding header.
+//
+// The streamed bodies and the responses that already have Content-Encoding are never compressed.
+type CompressMiddleware struct {
+	config *CompressConfig
+}
+
+// NewCompressMiddleware returns a CompressMiddleware's instance.
+//
+// The default configuration will be used if config is nil.
+func NewCompressMiddleware(config *CompressConfig) *CompressMiddleware {
+	if config == nil {
+		config = NewCompressConfig()
+	}
+	return &CompressMiddleware{config: config}
+}
+
+// Handle implemented Middleware Interface.
+func (m *CompressMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		next.Handle(ctx)
+
+		if !m.compressible(ctx) {
+			return
+		}
+
+		// The response varies on Accept-Encoding whether or not it is compressed.
+		addVary(ctx, "Accept-Encoding")
+
+		encoding := negotiateEncoding(ctx.Request.Header.Peek("Accept-Encoding"), m.config.Encodings)
+		if encoding == "" {
+			return
+		}
+
+		body := ctx.Response.Body()
+		var compressed []byte
+		switch encoding {
+		case EncodingBrotli:
+			compressed = fasthttp.AppendBrotliBytesLevel(nil, body, m.config.BrotliLevel)
+		case EncodingGzip:
+			compressed = fasthttp.AppendGzipBytesLevel(nil, body, m.config.GzipLevel)
+		case EncodingDeflate:
+			compressed = fasthttp.AppendDeflateBytesLevel(nil, body, m.config.DeflateLevel)
+		default:
+			return
+		}
+		if len(compressed) >= len(body) {
+			return
+		}
+
+		ctx.Response.SetBody(compressed)
+		ctx.Response.Header.Set("Content-Encoding", encoding)
+		// The compressed representation is no longer byte-for-byte identical.
+		if etag := ctx.Response.Header.Peek("ETag"); len(etag) > 0 && !bytes.HasPrefix(etag, []byte("W/")) {
+			ctx.Response.Header.Set("ETag", "W/"+string(etag))
+		}
+	})
+}
+
+// compressible reports whether the response should be compressed.
+func (m *CompressMiddleware) compressible(ctx *Context) bool {
+	if ctx.IsHead() || ctx.Response.IsBodyStream() {
+		return false
+	}
+
+	code := ctx.Response.StatusCode()
+	if code < 200 || code == fasthttp.StatusNoContent || code == fasthttp.StatusNotModified || code == fasthttp.StatusPartialContent {
+		return false
+	}
+
+	if len(ctx.Response.Header.Peek("Content-Encoding")) > 0 {
+		return false
+	}
+
+	if len(ctx.Response.Body()) < m.config.MinLength {
+		return false
+	}
+
+	if len(m.config.ContentTypes) == 0 {
+		return true
+	}
+	contentType := string(ctx.Response.Header.ContentType())
+	for _, prefix := range m.config.ContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding returns the supported encoding that has the highest quality in Accept-Encoding.
+//
+// The supported encodings are in order of preference, the earlier one wins if the qualities are equal.
+// Returns an empty string if none of the supported encodings is acceptable.
+func negotiateEncoding(acceptEncoding []byte, supported []string) string {
+	if len(acceptEncoding) == 0 {
+		return ""
+	}
+
+	qualities := make(map[string]float64)
+	for _, v := range strings.Split(string(acceptEncoding), ",") {
+		name, q := parseQuality(v)
+		if name != "" {
+			qualities[strings.ToLower(name)] = q
+		}
+	}
+
+	encoding, quality := "", 0.0
+	for _, v := range supported {
+		q, ok := qualities[v]
+		if !ok {
+			if q, ok = qualities["*"]; !ok {
+				continue
+			}
+		}
+		if q > quality {
+			encoding, quality = v, q
+		}
+	}
+	return encoding
+}
+
+// parseQuality parses the value and its quality of header, such as "gzip;q=0.8".
+func parseQuality(s string) (string, float64) {
+	parts := strings.Split(s, ";")
+	name := strings.TrimSpace(parts[0])
+	q := 1.0
+	for _, param := range parts[1:] {
+		param = strings.TrimSpace(param)
+		if strings.HasPrefix(param, "q=") {
+			if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				q = v
+			}
+		}
+	}
+	return name, q
+}
+
+// addVary adds the header name to Vary if it is not present.
+func addVary(ctx *Context, name string) {
+	vary := string(ctx.Response.Header.Peek("Vary"))
+	for _, v := range strings.Split(vary, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.EqualFold(v, name) {
+			return
+		}
+	}
+
+	if vary == "" {
+		ctx.Response.Header.Set("Vary", name)
+		return
+	}
+	ctx.Response.Header.Set("Vary", vary+", "+name)
+}
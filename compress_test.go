@@ -0,0 +1,119 @@
+package clevergo
+
+import (
+	"bytes"
+	"github.com/valyala/fasthttp"
+	"strings"
+	"testing"
+)
+
+func TestCompressMiddleware(t *testing.T) {
+	text := strings.Repeat("CleverGo ", 500)
+
+	r := NewRouter()
+	r.AddMiddleware(NewCompressMiddleware(nil))
+	r.GET("/text", HandlerFunc(func(ctx *Context) {
+		ctx.Response.Header.Set("ETag", `"v1"`)
+		ctx.HTML(text)
+	}))
+	r.GET("/small", HandlerFunc(func(ctx *Context) {
+		ctx.HTML("small")
+	}))
+	r.GET("/png", HandlerFunc(func(ctx *Context) {
+		ctx.SetContentType("image/png")
+		ctx.SetBodyString(text)
+	}))
+	r.GET("/encoded", HandlerFunc(func(ctx *Context) {
+		ctx.HTML(text)
+		ctx.Response.Header.Set("Content-Encoding", "identity")
+	}))
+	r.GET("/stream", HandlerFunc(func(ctx *Context) {
+		ctx.SetContentType("text/plain")
+		ctx.SetBodyStream(strings.NewReader(text), len(text))
+	}))
+
+	tests := []struct {
+		path           string
+		acceptEncoding string
+		encoding       string
+	}{
+		{"/text", "gzip", EncodingGzip},
+		{"/text", "deflate", EncodingDeflate},
+		{"/text", "gzip, deflate, br", EncodingBrotli},
+		{"/text", "br;q=0.5, gzip", EncodingGzip},
+		{"/text", "br;q=0, *", EncodingGzip},
+		{"/text", "identity", ""},
+		{"/text", "", ""},
+		{"/small", "gzip", ""},
+		{"/png", "gzip", ""},
+		{"/encoded", "gzip", "identity"},
+		{"/stream", "gzip", ""},
+	}
+
+	for _, test := range tests {
+		req := "GET " + test.path + " HTTP/1.1\r\n"
+		if test.acceptEncoding != "" {
+			req += "Accept-Encoding: " + test.acceptEncoding + "\r\n"
+		}
+		resp := serve(t, r.Handler, req+"\r\n")
+
+		encoding := string(resp.Header.Peek("Content-Encoding"))
+		if encoding != test.encoding {
+			t.Errorf("%s with %q: unexpected Content-Encoding %q. Expected %q", test.path, test.acceptEncoding, encoding, test.encoding)
+			continue
+		}
+
+		var body []byte
+		var err error
+		switch encoding {
+		case EncodingGzip:
+			body, err = fasthttp.AppendGunzipBytes(nil, resp.Body())
+		case EncodingDeflate:
+			body, err = fasthttp.AppendInflateBytes(nil, resp.Body())
+		case EncodingBrotli:
+			body, err = fasthttp.AppendUnbrotliBytes(nil, resp.Body())
+		default:
+			body = resp.Body()
+		}
+		if err != nil {
+			t.Errorf("%s with %q: failed to decompress body: %s", test.path, test.acceptEncoding, err)
+			continue
+		}
+		if test.path == "/text" && !bytes.Equal(body, []byte(text)) {
+			t.Errorf("%s with %q: unexpected body", test.path, test.acceptEncoding)
+		}
+
+		if test.path == "/text" {
+			if vary := string(resp.Header.Peek("Vary")); vary != "Accept-Encoding" {
+				t.Errorf("%s with %q: unexpected Vary %q", test.path, test.acceptEncoding, vary)
+			}
+			etag := string(resp.Header.Peek("ETag"))
+			if encoding != "" && etag != `W/"v1"` || encoding == "" && etag != `"v1"` {
+				t.Errorf("%s with %q: unexpected ETag %q", test.path, test.acceptEncoding, etag)
+			}
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := []string{EncodingBrotli, EncodingGzip, EncodingDeflate}
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", ""},
+		{"gzip", EncodingGzip},
+		{"deflate, gzip", EncodingGzip},
+		{"deflate;q=1.0, gzip;q=0.5", EncodingDeflate},
+		{"GZIP", EncodingGzip},
+		{"*", EncodingBrotli},
+		{"*;q=0", ""},
+		{"gzip;q=0", ""},
+	}
+
+	for _, test := range tests {
+		if encoding := negotiateEncoding([]byte(test.acceptEncoding), supported); encoding != test.expected {
+			t.Errorf("negotiateEncoding(%q) = %q, expect %q", test.acceptEncoding, encoding, test.expected)
+		}
+	}
+}
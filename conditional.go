@@ -0,0 +1,144 @@
+package clevergo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrPreconditionFailed means that the conditional headers of the request are not satisfied.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// NotModified sets the ETag and Last-Modified headers of the resource, and evaluates the conditional headers
+// If-Match, If-Unmodified-Since, If-None-Match and If-Modified-Since as RFC 7232.
+//
+// It returns true if the response is finished, with 304 for the fresh GET and HEAD requests,
+// or 412 handled by Context.HandleError for the failed preconditions, then the handler should return early.
+// The unsafe methods, such as PUT and DELETE, should call it before modifying the resource.
+//
+// The unquoted etag is quoted as a strong entity tag, the empty etag and zero modtime are ignored.
+func (ctx *Context) NotModified(etag string, modtime time.Time) bool {
+	ctx.checkReleased()
+	if etag != "" {
+		if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+			etag = strconv.Quote(etag)
+		}
+		ctx.Response.Header.Set("ETag", etag)
+	}
+	if !isZeroTime(modtime) {
+		ctx.Response.Header.SetLastModified(modtime)
+	}
+
+	switch checkPreconditions(ctx, etag, modtime) {
+	case fasthttp.StatusNotModified:
+		writeNotModified(ctx)
+		return true
+	case fasthttp.StatusPreconditionFailed:
+		ctx.HandleError(fasthttp.StatusPreconditionFailed, ErrPreconditionFailed)
+		return true
+	}
+	return false
+}
+
+// checkPreconditions evaluates the conditional headers in order of RFC 7232 section 6,
+// returns 304, 412, or 0 if the request should be handled.
+func checkPreconditions(ctx *Context, etag string, modtime time.Time) int {
+	if im := ctx.Request.Header.Peek("If-Match"); len(im) > 0 {
+		if etag == "" || !etagMatch(string(im), etag, false) {
+			return fasthttp.StatusPreconditionFailed
+		}
+	} else if t, ok := parseHTTPDate(ctx.Request.Header.Peek("If-Unmodified-Since")); ok && !isZeroTime(modtime) {
+		if modtime.Truncate(time.Second).After(t) {
+			return fasthttp.StatusPreconditionFailed
+		}
+	}
+
+	safe := ctx.IsGet() || ctx.IsHead()
+	if inm := ctx.Request.Header.Peek("If-None-Match"); len(inm) > 0 {
+		if etag == "" || !etagMatch(string(inm), etag, true) {
+			return 0
+		}
+		if safe {
+			return fasthttp.StatusNotModified
+		}
+		return fasthttp.StatusPreconditionFailed
+	}
+	if t, ok := parseHTTPDate(ctx.Request.Header.Peek("If-Modified-Since")); ok && safe && !isZeroTime(modtime) {
+		if !modtime.Truncate(time.Second).After(t) {
+			return fasthttp.StatusNotModified
+		}
+	}
+	return 0
+}
+
+func parseHTTPDate(value []byte) (time.Time, bool) {
+	if len(value) == 0 {
+		return time.Time{}, false
+	}
+	t, err := fasthttp.ParseHTTPDate(value)
+	return t, err == nil
+}
+
+// writeNotModified responses 304, unlike fasthttp.RequestCtx.NotModified,
+// the ETag, Last-Modified and caching headers are kept as RFC 7232 section 4.1.
+func writeNotModified(ctx *Context) {
+	ctx.Response.ResetBody()
+	ctx.Response.Header.Del("Content-Encoding")
+	ctx.SetStatusCode(fasthttp.StatusNotModified)
+}
+
+// ETagConfig for ETagMiddleware.
+type ETagConfig struct {
+	// Weak generates the weak entity tags, which should be used if the body is transformed afterwards,
+	// such as being compressed by the outer middlewares.
+	Weak bool
+}
+
+// NewETagConfig returns default ETag configuration, which generates the weak entity tags.
+func NewETagConfig() *ETagConfig {
+	return &ETagConfig{
+		Weak: true,
+	}
+}
+
+// ETagMiddleware generates the ETag header from the buffered body of the successful GET and HEAD responses,
+// and evaluates the conditional headers with the ETag and Last-Modified headers, see Context.NotModified.
+//
+// The responses which already have ETag, such as the static files, and the streamed responses are skipped.
+type ETagMiddleware struct {
+	config *ETagConfig
+}
+
+// NewETagMiddleware returns an ETagMiddleware's instance.
+//
+// The default configuration will be used if config is nil.
+func NewETagMiddleware(config *ETagConfig) *ETagMiddleware {
+	if config == nil {
+		config = NewETagConfig()
+	}
+	return &ETagMiddleware{config: config}
+}
+
+// Handle implemented Middleware Interface.
+func (m *ETagMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		next.Handle(ctx)
+
+		if !ctx.IsGet() && !ctx.IsHead() || ctx.Response.StatusCode() != fasthttp.StatusOK ||
+			ctx.Response.IsBodyStream() || len(ctx.Response.Header.Peek("ETag")) > 0 {
+			return
+		}
+
+		sum := sha256.Sum256(ctx.Response.Body())
+		etag := `"` + strconv.FormatInt(int64(len(ctx.Response.Body())), 16) + "-" + hex.EncodeToString(sum[:12]) + `"`
+		if m.config.Weak {
+			etag = "W/" + etag
+		}
+		modtime, _ := parseHTTPDate(ctx.Response.Header.Peek("Last-Modified"))
+		ctx.NotModified(etag, modtime)
+	})
+}
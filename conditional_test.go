@@ -0,0 +1,90 @@
+package clevergo
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestETagMiddleware(t *testing.T) {
+	r := NewRouter()
+	r.AddMiddleware(NewETagMiddleware(nil))
+	r.GET("/users", HandlerFunc(func(ctx *Context) {
+		ctx.Response.Header.SetLastModified(staticModTime)
+		ctx.JSON(map[string]string{"name": "foo"})
+	}))
+	r.GET("/stream", HandlerFunc(func(ctx *Context) {
+		ctx.SetBodyStream(strings.NewReader("stream"), -1)
+	}))
+	r.POST("/users", HandlerFunc(func(ctx *Context) {
+		ctx.Text("created")
+	}))
+
+	resp := serve(t, r.Handler, "GET /users HTTP/1.1\r\n\r\n")
+	etag := string(resp.Header.Peek("ETag"))
+	if resp.StatusCode() != 200 || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("unexpected response %d, ETag %q", resp.StatusCode(), etag)
+	}
+	lastModified := staticModTime.Format(time.RFC1123)
+
+	tests := []struct {
+		request string
+		code    int
+	}{
+		{"GET /users HTTP/1.1\r\nIf-None-Match: " + etag + "\r\n\r\n", 304},
+		{"GET /users HTTP/1.1\r\nIf-None-Match: \"other\", " + strings.TrimPrefix(etag, "W/") + "\r\n\r\n", 304},
+		{"GET /users HTTP/1.1\r\nIf-None-Match: \"other\"\r\n\r\n", 200},
+		{"GET /users HTTP/1.1\r\nIf-Modified-Since: " + lastModified + "\r\n\r\n", 304},
+		{"GET /users HTTP/1.1\r\nIf-None-Match: \"other\"\r\nIf-Modified-Since: " + lastModified + "\r\n\r\n", 200},
+		// Weak entity tags never match If-Match.
+		{"GET /users HTTP/1.1\r\nIf-Match: " + etag + "\r\n\r\n", 412},
+		{"GET /users HTTP/1.1\r\nIf-Match: *\r\n\r\n", 200},
+		{"GET /users HTTP/1.1\r\nIf-Unmodified-Since: " + staticModTime.Add(-time.Hour).Format(time.RFC1123) + "\r\n\r\n", 412},
+		{"GET /stream HTTP/1.1\r\nIf-None-Match: *\r\n\r\n", 200},
+		{"POST /users HTTP/1.1\r\nIf-None-Match: *\r\n\r\n", 200},
+	}
+	for i, test := range tests {
+		resp = serve(t, r.Handler, test.request)
+		if resp.StatusCode() != test.code {
+			t.Errorf("%d: unexpected status code %d. Expected %d", i, resp.StatusCode(), test.code)
+		}
+		if test.code == 304 && (string(resp.Header.Peek("ETag")) != etag || len(resp.Body()) > 0) {
+			t.Errorf("%d: unexpected 304 response, ETag %q, body %q", i, resp.Header.Peek("ETag"), resp.Body())
+		}
+	}
+}
+
+func TestContext_NotModified(t *testing.T) {
+	updated := 0
+	r := NewRouter()
+	r.PUT("/posts/1", HandlerFunc(func(ctx *Context) {
+		if ctx.NotModified("v2", time.Time{}) {
+			return
+		}
+		updated++
+	}))
+	r.GET("/posts/1", HandlerFunc(func(ctx *Context) {
+		if ctx.NotModified(`W/"v2"`, staticModTime) {
+			return
+		}
+		ctx.Text("post")
+	}))
+
+	tests := []struct {
+		request string
+		code    int
+		updated int
+	}{
+		{"PUT /posts/1 HTTP/1.1\r\nIf-Match: \"v1\"\r\n\r\n", 412, 0},
+		{"PUT /posts/1 HTTP/1.1\r\nIf-Match: \"v2\"\r\n\r\n", 200, 1},
+		{"PUT /posts/1 HTTP/1.1\r\nIf-None-Match: *\r\n\r\n", 412, 1},
+		{"GET /posts/1 HTTP/1.1\r\nIf-None-Match: \"v2\"\r\n\r\n", 304, 1},
+		{"GET /posts/1 HTTP/1.1\r\nIf-Modified-Since: " + staticModTime.Format(time.RFC1123) + "\r\n\r\n", 304, 1},
+	}
+	for i, test := range tests {
+		resp := serve(t, r.Handler, test.request)
+		if resp.StatusCode() != test.code || updated != test.updated {
+			t.Errorf("%d: unexpected status code %d and updated %d. Expected %d and %d", i, resp.StatusCode(), updated, test.code, test.updated)
+		}
+	}
+}
@@ -23,6 +23,9 @@ type Config struct {
 	ServerKeyFile  string      // KeyFile  for TLS application.
 	ServerCertData []byte      // CertData  for TLSEmbed application.
 	ServerKeyData  []byte      // KeyData  for TLSEmbed application.
+
+	ServerMaxRequestBodySize int  // Max size of request body, zero means fasthttp's default 4MB.
+	ServerStreamRequestBody  bool // Stream the request body instead of buffering it, see UploadMiddleware.
 }
 
 // NewConfig returns default configuration.
@@ -1,6 +1,8 @@
 package clevergo
 
 import (
+	"container/list"
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -8,7 +10,10 @@ import (
 	"github.com/clevergo/sessions"
 	"github.com/valyala/fasthttp"
 	"html/template"
+	"mime/multipart"
+	"runtime/debug"
 	"sync"
+	"time"
 )
 
 var contextPool = &sync.Pool{
@@ -23,8 +28,24 @@ var contextPool = &sync.Pool{
 type Context struct {
 	router *Router
 	*fasthttp.RequestCtx
-	Params  *router.Params
-	Session *sessions.Session
+	Params          *router.Params
+	Session         *sessions.Session
+	sessionName     string                      // name of session, set by SessionMiddleware.
+	sessionSnapshot map[interface{}]interface{} // session values when the session was loaded or saved.
+	sessionModified bool                        // whether the session was marked as modified.
+	csrfToken       string                      // masked CSRF token of the current request.
+	templateFuncs   template.FuncMap            // request-scoped template functions.
+	principal       interface{}                 // authenticated principal.
+	route           string                      // path of the matched route.
+	cspNonce        string                      // nonce of Content-Security-Policy.
+	stdContext      context.Context             // context.Context of the current request.
+	cancel          context.CancelFunc          // cancels the stdContext.
+	values          map[string]interface{}      // request-scoped values, see Set and Get.
+	uploadConfig    *UploadConfig               // upload configuration, set by UploadMiddleware.
+	uploadForm      *multipart.Form             // multipart form parsed by UploadMiddleware.
+	cacheTags       []string                    // tags of cached response, see AddCacheTags.
+	acquiredStack   []byte                      // stack of acquiring in debug mode.
+	releasedStack   []byte                      // stack of releasing in debug mode, non-nil means poisoned.
 }
 
 // NewContext returns a Context instance.
@@ -33,39 +54,231 @@ type Context struct {
 // If failed to get Context from contextPool,
 // returns a new Context instance.
 func NewContext(r *Router, ctx *fasthttp.RequestCtx, rps *router.Params) *Context {
-	if context, ok := contextPool.Get().(*Context); ok {
-		context.router = r
-		context.RequestCtx = ctx
-		context.Params = rps
-		return context
+	context, ok := contextPool.Get().(*Context)
+	if !ok {
+		context = &Context{}
 	}
-
-	return &Context{
-		router:     r,
-		RequestCtx: ctx,
-		Params:     rps,
+	context.router = r
+	context.RequestCtx = ctx
+	context.Params = rps
+	if debugMode {
+		context.acquiredStack = debug.Stack()
 	}
+	return context
 }
 
 // Close Context.
 //
 // Context should be closed after finishing request,
-// and at this moment, put the context into contextPool.
+// and at this moment, all of the request's state is reset, and the context is put into contextPool.
+//
+// In debug mode, the context is poisoned instead of being reused, see SetDebug.
 func (ctx *Context) Close() {
-	ctx.Session = nil
+	ctx.checkReleased()
+	if ctx.cancel != nil {
+		ctx.cancel()
+	}
+
+	values, acquired := ctx.values, ctx.acquiredStack
+	// Keep the map for reusing.
+	for k := range values {
+		delete(values, k)
+	}
+	*ctx = Context{values: values}
+
+	if debugMode {
+		ctx.poison(acquired)
+		return
+	}
 	contextPool.Put(ctx)
 }
 
+// Copy returns a copy of the context which is safe to be used after the request is finished,
+// such as handing off to a goroutine.
+//
+// The copy contains the request, params, a copy of session, principal and request-scoped values,
+// its Context.Context keeps the values but it is not canceled with the request.
+// The response of the copy is not sent to the client.
+func (ctx *Context) Copy() *Context {
+	ctx.checkReleased()
+
+	c := &Context{
+		router:          ctx.router,
+		RequestCtx:      &fasthttp.RequestCtx{},
+		sessionName:     ctx.sessionName,
+		sessionModified: ctx.sessionModified,
+		csrfToken:       ctx.csrfToken,
+		principal:       ctx.principal,
+		route:           ctx.route,
+		cspNonce:        ctx.cspNonce,
+	}
+	c.RequestCtx.Init(&ctx.Request, ctx.RemoteAddr(), ctx.Logger())
+	if ctx.Session != nil {
+		// The session values are copied, so that the copy doesn't race with the request.
+		session := *ctx.Session
+		session.Values = copySessionValues(ctx.Session.Values)
+		if session.Options != nil {
+			options := *session.Options
+			session.Options = &options
+		}
+		c.Session = &session
+	}
+	if ctx.sessionSnapshot != nil {
+		c.sessionSnapshot = copySessionValues(ctx.sessionSnapshot)
+	}
+	if ctx.Params != nil {
+		params := make(router.Params, len(*ctx.Params))
+		copy(params, *ctx.Params)
+		c.Params = &params
+	}
+	if len(ctx.templateFuncs) > 0 {
+		c.templateFuncs = make(template.FuncMap, len(ctx.templateFuncs))
+		for k, v := range ctx.templateFuncs {
+			c.templateFuncs[k] = v
+		}
+	}
+	if len(ctx.values) > 0 {
+		c.values = make(map[string]interface{}, len(ctx.values))
+		for k, v := range ctx.values {
+			c.values[k] = v
+		}
+	}
+	if ctx.stdContext != nil {
+		c.stdContext = detachedContext{ctx.stdContext}
+	}
+	return c
+}
+
+// detachedContext keeps the values of parent, but it is never canceled.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// Route returns the path of the matched route, such as "/users/:id".
+//
+// Returns an empty string if the request is not handled by a registered route.
+func (ctx *Context) Route() string {
+	ctx.checkReleased()
+	return ctx.route
+}
+
+// Context returns the context.Context of the current request, which should be passed to
+// the downstream calls, such as database queries and HTTP requests.
+//
+// It is canceled when the request is finished, when the client closes the connection
+// and when the server is shutting down, and the deadline is set by TimeoutMiddleware.
+// The closed connection is detected within 100 milliseconds on Unix-like systems.
+func (ctx *Context) Context() context.Context {
+	ctx.checkReleased()
+	if ctx.stdContext == nil {
+		var parent context.Context = context.Background()
+		if done := requestDone(ctx.RequestCtx); done != nil {
+			parent = requestContext{done: done}
+		}
+		ctx.stdContext, ctx.cancel = context.WithCancel(parent)
+		ctx.watchDisconnect()
+	}
+	return ctx.stdContext
+}
+
+// requestDone returns the channel which is closed when the server is shutting down,
+// or nil if the request is not served by a server, such as the zero fasthttp.RequestCtx in tests.
+func requestDone(ctx *fasthttp.RequestCtx) (done <-chan struct{}) {
+	if ctx == nil {
+		return nil
+	}
+	defer func() {
+		if recover() != nil {
+			done = nil
+		}
+	}()
+	return ctx.Done()
+}
+
+// requestContext is the parent of Context.Context, which is canceled when the server is shutting down.
+//
+// Unlike fasthttp.RequestCtx, it doesn't refer to the request, which is reused after the request is finished.
+type requestContext struct {
+	done <-chan struct{}
+}
+
+func (requestContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (c requestContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c requestContext) Err() error {
+	select {
+	case <-c.done:
+		return context.Canceled
+	default:
+		return nil
+	}
+}
+
+func (requestContext) Value(key interface{}) interface{} {
+	return nil
+}
+
+// SetContext replaces the context.Context of the current request, the context should be
+// derived from Context.Context, so that it is canceled when the request is finished.
+func (ctx *Context) SetContext(c context.Context) {
+	ctx.checkReleased()
+	ctx.Context()
+	ctx.stdContext = c
+}
+
+// WithValue adds the key/value pair to the context.Context of the current request,
+// so that it is propagated to the downstream calls.
+func (ctx *Context) WithValue(key, value interface{}) {
+	ctx.checkReleased()
+	ctx.stdContext = context.WithValue(ctx.Context(), key, value)
+}
+
 // SessionStore returns the session store of router.
 func (ctx *Context) SessionStore() sessions.Store {
+	ctx.checkReleased()
 	return ctx.router.sessionStore
 }
 
+// HandleError handles the error with HTTP status code.
+//
+// The router's error handler will be invoked if it is non-nil.
+// Otherwise, responses the status message to client.
+func (ctx *Context) HandleError(code int, err error) {
+	ctx.checkReleased()
+	if ctx.router.errorHandler != nil {
+		ctx.router.errorHandler(ctx, code, err)
+		return
+	}
+	ctx.Error(fasthttp.StatusMessage(code), code)
+}
+
 // Logger returns logger.
 //
 // Returns the router's logger if the logger is non-nil.
 // Otherwise, returns the default logger of ctx.
 func (ctx *Context) Logger() fasthttp.Logger {
+	ctx.checkReleased()
 	if ctx.router.logger != nil {
 		return ctx.router.logger
 	}
@@ -85,26 +298,31 @@ const (
 
 // SetContentTypeToHTML set Content-Type to HTML.
 func (ctx *Context) SetContentTypeToHTML() {
+	ctx.checkReleased()
 	ctx.Response.Header.Set("Content-Type", contentTypeHTML)
 }
 
 // SetContentTypeToJSON set Content-Type to JSON.
 func (ctx *Context) SetContentTypeToJSON() {
+	ctx.checkReleased()
 	ctx.Response.Header.Set("Content-Type", contentTypeJSON)
 }
 
 // SetContentTypeToJSONP set Content-Type to JSONP.
 func (ctx *Context) SetContentTypeToJSONP() {
+	ctx.checkReleased()
 	ctx.Response.Header.Set("Content-Type", contentTypeJSONP)
 }
 
 // SetContentTypeToXML set Content-Type to XML.
 func (ctx *Context) SetContentTypeToXML() {
+	ctx.checkReleased()
 	ctx.Response.Header.Set("Content-Type", contentTypeXML)
 }
 
 // JSON responses JSON data to client.
 func (ctx *Context) JSON(v interface{}) {
+	ctx.checkReleased()
 	json, err := json.Marshal(v)
 	if err != nil {
 		fmt.Fprint(ctx, err.Error())
@@ -116,12 +334,14 @@ func (ctx *Context) JSON(v interface{}) {
 
 // JSONWithCode responses JSON data and custom status code to client.
 func (ctx *Context) JSONWithCode(code int, v interface{}) {
+	ctx.checkReleased()
 	ctx.Response.SetStatusCode(code)
 	ctx.JSON(v)
 }
 
 // JSONP responses JSONP data to client.
 func (ctx *Context) JSONP(v interface{}, callback []byte) {
+	ctx.checkReleased()
 	json, err := json.Marshal(v)
 	if err != nil {
 		fmt.Fprint(ctx, err.Error())
@@ -136,12 +356,14 @@ func (ctx *Context) JSONP(v interface{}, callback []byte) {
 
 // JSONPWithCode responses JSONP data and custom status code to client.
 func (ctx *Context) JSONPWithCode(code int, v interface{}, callback []byte) {
+	ctx.checkReleased()
 	ctx.Response.SetStatusCode(code)
 	ctx.JSONP(v, callback)
 }
 
 // XML responses XML data to client.
 func (ctx *Context) XML(v interface{}, headers ...string) {
+	ctx.checkReleased()
 	xmlBytes, err := xml.MarshalIndent(v, "", `   `)
 	if err != nil {
 		fmt.Fprint(ctx, err.Error())
@@ -163,34 +385,137 @@ func (ctx *Context) XML(v interface{}, headers ...string) {
 
 // XMLWithCode responses XML data and custom status code to client.
 func (ctx *Context) XMLWithCode(code int, v interface{}, headers ...string) {
+	ctx.checkReleased()
 	ctx.Response.SetStatusCode(code)
 	ctx.XML(v, headers...)
 }
 
 // HTML responses HTML data to client.
 func (ctx *Context) HTML(body string) {
+	ctx.checkReleased()
 	ctx.SetContentTypeToHTML()
 	ctx.Response.SetBodyString(body)
 }
 
 // HTMLWithCode responses HTML data and custom status code to client.
 func (ctx *Context) HTMLWithCode(code int, body string) {
+	ctx.checkReleased()
 	ctx.Response.SetStatusCode(code)
 	ctx.HTML(body)
 }
 
 // Text responses text data to client using fmt.Fprint().
 func (ctx *Context) Text(a ...interface{}) {
+	ctx.checkReleased()
 	fmt.Fprint(ctx, a...)
 }
 
 // Textf responses text data to client using fmt.Fprintf().
 func (ctx *Context) Textf(format string, a ...interface{}) {
+	ctx.checkReleased()
 	fmt.Fprintf(ctx, format, a...)
 }
 
+// SetTemplateFunc set a request-scoped template function, which is used by Render.
+//
+// The template should be parsed with the function of the same name,
+// see also TemplateFuncs.
+func (ctx *Context) SetTemplateFunc(name string, fn interface{}) {
+	ctx.checkReleased()
+	if ctx.templateFuncs == nil {
+		ctx.templateFuncs = make(template.FuncMap)
+	}
+	ctx.templateFuncs[name] = fn
+}
+
 // Render for rendering a template.
+//
+// The template is rendered by a clone of it with the request-scoped template functions,
+// the original one is never executed, so that it can be rendered by the concurrent requests.
+// The templates should be parsed once and reused, since the router keeps an unexecuted clone of each template.
+// The templates which have been executed by others can not be cloned, it responses status code 500.
 func (ctx *Context) Render(tpl *template.Template, data interface{}) {
+	ctx.checkReleased()
+	master, err := ctx.router.templates.get(tpl)
+	var clone *template.Template
+	if err == nil {
+		clone, err = master.Clone()
+	}
+	if err != nil {
+		ctx.Logger().Printf("Render: failed to clone template %q: %s", tpl.Name(), err)
+		ctx.HandleError(fasthttp.StatusInternalServerError, err)
+		return
+	}
+	if len(ctx.templateFuncs) > 0 {
+		clone.Funcs(ctx.templateFuncs)
+	}
 	ctx.SetContentTypeToHTML()
-	tpl.Execute(ctx, data)
+	clone.Execute(ctx, data)
+}
+
+// templateFuncsPlaceholder returns empty string,
+// it would be replaced by the request-scoped template function.
+func templateFuncsPlaceholder() string {
+	return ""
+}
+
+// TemplateFuncs returns the placeholders of request-scoped template functions,
+// the templates rendered by Context.Render should be parsed with them.
+//
+// For example:
+//
+//	tpl := template.Must(template.New("form").Funcs(clevergo.TemplateFuncs()).Parse(`<form>{{ csrfField }}</form>`))
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"csrfToken": templateFuncsPlaceholder,
+		"csrfField": func() template.HTML { return "" },
+		"cspNonce":  templateFuncsPlaceholder,
+	}
+}
+
+// maxTemplateMasters is the max number of the unexecuted clones kept by a router.
+const maxTemplateMasters = 1024
+
+// templateMasters contains the unexecuted clones of templates, keyed by the original templates.
+//
+// The least recently used ones are evicted when full, so that the templates parsed per request
+// or per tenant don't leak memory.
+type templateMasters struct {
+	mu      sync.Mutex
+	list    *list.List // Most recently used at front.
+	masters map[*template.Template]*list.Element
+}
+
+type templateMaster struct {
+	tpl    *template.Template
+	master *template.Template
+}
+
+// get returns the unexecuted clone of the template,
+// so that the request-scoped functions can be applied to its clones without affecting the other requests.
+//
+// Returns an error if the template has been executed.
+func (tm *templateMasters) get(tpl *template.Template) (*template.Template, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if e, ok := tm.masters[tpl]; ok {
+		tm.list.MoveToFront(e)
+		return e.Value.(*templateMaster).master, nil
+	}
+
+	master, err := tpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if tm.masters == nil {
+		tm.list = list.New()
+		tm.masters = make(map[*template.Template]*list.Element)
+	}
+	tm.masters[tpl] = tm.list.PushFront(&templateMaster{tpl: tpl, master: master})
+	if tm.list.Len() > maxTemplateMasters {
+		e := tm.list.Back()
+		tm.list.Remove(e)
+		delete(tm.masters, e.Value.(*templateMaster).tpl)
+	}
+	return master, nil
 }
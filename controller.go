@@ -1,88 +1,102 @@
 package clevergo
 
-// ControllerInterface contains the methods which the Controller should implements.
+// ControllerInterface is implemented by the controllers.
 //
-// In fact, the controller is a middleware.
+// In fact, the controller is a middleware, which wraps the request handlers of the methods it implements,
+// such as GET(ctx *Context), see GETHandler, POSTHandler etc.
 type ControllerInterface interface {
 	Handle(next Handler) Handler // Implemented Middleware Interface.
-
-	initMiddlewares(next Handler) Handler // Init the middlewares.
-
-	DELETE(ctx *Context)  // Request handler for DELETE request.
-	GET(ctx *Context)     // Request handler for GET request.
-	HEAD(ctx *Context)    // Request handler for HEAD request.
-	OPTIONS(ctx *Context) // Request handler for OPTIONS request.
-	PATCH(ctx *Context)   // Request handler for PATCH request.
-	POST(ctx *Context)    // Request handler for POST request.
-	PUT(ctx *Context)     // Request handler for PUT request.
 }
 
-// Controller the conventional RESTful API Controller.
-//
-// Middlewares for the current controller.
-// Important note: the Controller just a sample,
-// it shows that how to create a highly scalable RESTful controller.
-// Example: https://github.com/headwindfly/clevergo/blob/master/examples/restful.
-type Controller struct {
-	Middlewares []Middleware
+// GETHandler is implemented by the controllers which handle GET requests.
+type GETHandler interface {
+	GET(ctx *Context)
 }
 
-func (c *Controller) AddMiddleware(m Middleware) {
-	c.Middlewares = append(c.Middlewares, m)
+// HEADHandler is implemented by the controllers which handle HEAD requests.
+type HEADHandler interface {
+	HEAD(ctx *Context)
 }
 
-// initMiddlewares initialize the controller's middleware.
-// Make the controller's handler wraped by additional middlewares.
-func (c Controller) initMiddlewares(next Handler) Handler {
-	var h Handler
-	h = c.Handle(next)
-
-	for i := len(c.Middlewares) - 1; i >= 0; i-- {
-		h = c.Middlewares[i].Handle(h)
-	}
-
-	return h
+// POSTHandler is implemented by the controllers which handle POST requests.
+type POSTHandler interface {
+	POST(ctx *Context)
 }
 
-// Handle implemented Middleware Interface.
-func (c Controller) Handle(next Handler) Handler {
-	return HandlerFunc(func(ctx *Context) {
-		// Invoke the request handler.
-		next.Handle(ctx)
-	})
+// PUTHandler is implemented by the controllers which handle PUT requests.
+type PUTHandler interface {
+	PUT(ctx *Context)
 }
 
-// DELETE for handling the DELETE request.
-func (c Controller) DELETE(ctx *Context) {
-	ctx.NotFound()
+// PATCHHandler is implemented by the controllers which handle PATCH requests.
+type PATCHHandler interface {
+	PATCH(ctx *Context)
 }
 
-// GET for handling the GET request.
-func (c Controller) GET(ctx *Context) {
-	ctx.NotFound()
+// DELETEHandler is implemented by the controllers which handle DELETE requests.
+type DELETEHandler interface {
+	DELETE(ctx *Context)
 }
 
-// HEAD for handling the HEAD request.
-func (c Controller) HEAD(ctx *Context) {
-	ctx.NotFound()
+// OPTIONSHandler is implemented by the controllers which handle OPTIONS requests.
+type OPTIONSHandler interface {
+	OPTIONS(ctx *Context)
 }
 
-// OPTIONS for handling the OPTIONS request.
-func (c Controller) OPTIONS(ctx *Context) {
-	ctx.NotFound()
+// controllerHandlers returns the request handlers of the methods which the controller implements,
+// in order of routeMethods.
+func controllerHandlers(c ControllerInterface) ([]string, []Handler) {
+	var (
+		methods  []string
+		handlers []Handler
+	)
+	add := func(method string, handler func(*Context)) {
+		methods = append(methods, method)
+		handlers = append(handlers, HandlerFunc(handler))
+	}
+	if h, ok := c.(GETHandler); ok {
+		add("GET", h.GET)
+	}
+	if h, ok := c.(HEADHandler); ok {
+		add("HEAD", h.HEAD)
+	}
+	if h, ok := c.(POSTHandler); ok {
+		add("POST", h.POST)
+	}
+	if h, ok := c.(PUTHandler); ok {
+		add("PUT", h.PUT)
+	}
+	if h, ok := c.(PATCHHandler); ok {
+		add("PATCH", h.PATCH)
+	}
+	if h, ok := c.(DELETEHandler); ok {
+		add("DELETE", h.DELETE)
+	}
+	if h, ok := c.(OPTIONSHandler); ok {
+		add("OPTIONS", h.OPTIONS)
+	}
+	return methods, handlers
 }
 
-// PATCH for handling the PATCH request.
-func (c Controller) PATCH(ctx *Context) {
-	ctx.NotFound()
+// Controller the conventional RESTful API Controller, which is intended to be embedded,
+// the embedding type implements the methods it handles, such as GET(ctx *Context).
+//
+// Important note: the Controller just a sample,
+// it shows that how to create a highly scalable RESTful controller.
+// Example: https://github.com/headwindfly/clevergo/blob/master/examples/restful.
+type Controller struct {
+	Middlewares []Middleware // Middlewares for the current controller.
 }
 
-// POST for handling the POST request.
-func (c Controller) POST(ctx *Context) {
-	ctx.NotFound()
+// AddMiddleware add middleware.
+func (c *Controller) AddMiddleware(m Middleware) {
+	c.Middlewares = append(c.Middlewares, m)
 }
 
-// PUT for handling the PUT request.
-func (c Controller) PUT(ctx *Context) {
-	ctx.NotFound()
+// Handle implemented Middleware Interface.
+//
+// It wraps the request handler by the controller's middlewares, the embedding type which overrides it
+// should call Controller.Handle to keep the middlewares.
+func (c Controller) Handle(next Handler) Handler {
+	return Chain(next, c.Middlewares...)
 }
@@ -0,0 +1,234 @@
+package clevergo
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+var (
+	// ErrNoSecretKey means that there is no secret key for signing and encrypting cookies.
+	ErrNoSecretKey = errors.New("no secret key")
+	// ErrCookieMissing means that the cookie doesn't exist.
+	ErrCookieMissing = errors.New("missing cookie")
+	// ErrInvalidCookie means that the cookie is malformed, or its signature doesn't match.
+	ErrInvalidCookie = errors.New("invalid cookie")
+	// ErrCookieExpired means that the cookie has expired.
+	ErrCookieExpired = errors.New("cookie expired")
+)
+
+// CookieConfig for setting cookies.
+type CookieConfig struct {
+	Path     string                  // Path of cookie.
+	Domain   string                  // Domain of cookie, empty means the current host only.
+	MaxAge   int                     // Max age in seconds, zero means a session cookie, negative deletes the cookie.
+	Secure   bool                    // Secure attribute, it is always set on HTTPS requests.
+	HTTPOnly bool                    // HttpOnly attribute.
+	SameSite fasthttp.CookieSameSite // SameSite attribute, the Secure attribute is set if it is None.
+}
+
+// NewCookieConfig returns default cookie configuration, the cookies are HttpOnly and SameSite=Lax.
+func NewCookieConfig() *CookieConfig {
+	return &CookieConfig{
+		Path:     "/",
+		HTTPOnly: true,
+		SameSite: fasthttp.CookieSameSiteLaxMode,
+	}
+}
+
+// cookieKey contains the keys derived from a secret key.
+type cookieKey struct {
+	hash []byte      // HMAC-SHA256 key of signed cookies.
+	aead cipher.AEAD // AES-256-GCM of encrypted cookies.
+}
+
+// newCookieKey derives the signing and encryption keys from the secret key.
+func newCookieKey(secret []byte) cookieKey {
+	if len(secret) == 0 {
+		panic("clevergo: empty secret key")
+	}
+	derive := func(purpose string) []byte {
+		h := hmac.New(sha256.New, secret)
+		h.Write([]byte(purpose))
+		return h.Sum(nil)
+	}
+	block, err := aes.NewCipher(derive("clevergo cookie encryption"))
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return cookieKey{hash: derive("clevergo cookie signing"), aead: aead}
+}
+
+// Cookie returns the value of request cookie, or an empty string if it doesn't exist.
+func (ctx *Context) Cookie(name string) string {
+	ctx.checkReleased()
+	return string(ctx.Request.Header.Cookie(name))
+}
+
+// SetCookie sets the response cookie, nil config means default configuration.
+func (ctx *Context) SetCookie(name, value string, config *CookieConfig) {
+	ctx.checkReleased()
+	if config == nil {
+		config = NewCookieConfig()
+	}
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+	cookie.SetKey(name)
+	cookie.SetValue(value)
+	cookie.SetPath(config.Path)
+	cookie.SetDomain(config.Domain)
+	if config.MaxAge < 0 {
+		cookie.SetExpire(fasthttp.CookieExpireDelete)
+	} else if config.MaxAge > 0 {
+		cookie.SetMaxAge(config.MaxAge)
+		cookie.SetExpire(time.Now().Add(time.Duration(config.MaxAge) * time.Second))
+	}
+	cookie.SetHTTPOnly(config.HTTPOnly)
+	cookie.SetSameSite(config.SameSite)
+	cookie.SetSecure(config.Secure || config.SameSite == fasthttp.CookieSameSiteNoneMode || ctx.Scheme() == "https")
+	ctx.Response.Header.SetCookie(cookie)
+}
+
+// DeleteCookie expires the cookie, the config's path and domain should be same as setting.
+func (ctx *Context) DeleteCookie(name string, config *CookieConfig) {
+	ctx.checkReleased()
+	if config == nil {
+		config = NewCookieConfig()
+	}
+	deleted := *config
+	deleted.MaxAge = -1
+	ctx.SetCookie(name, "", &deleted)
+}
+
+// SetSignedCookie sets the cookie signed by the router's secret key, the value is readable by the client but
+// can not be tampered, see Router.SetSecretKeys. The cookie expires on the server side as well if MaxAge is positive.
+func (ctx *Context) SetSignedCookie(name, value string, config *CookieConfig) error {
+	ctx.checkReleased()
+	encoded, err := ctx.encodeCookie(name, value, config, false)
+	if err != nil {
+		return err
+	}
+	ctx.SetCookie(name, encoded, config)
+	return nil
+}
+
+// SignedCookie returns the value of the signed cookie, which is verified by trying all secret keys in order.
+func (ctx *Context) SignedCookie(name string) (string, error) {
+	ctx.checkReleased()
+	return ctx.decodeCookie(name, false)
+}
+
+// SetEncryptedCookie sets the cookie encrypted by the router's secret key, the value is neither readable
+// nor tamperable by the client, see Router.SetSecretKeys.
+func (ctx *Context) SetEncryptedCookie(name, value string, config *CookieConfig) error {
+	ctx.checkReleased()
+	encoded, err := ctx.encodeCookie(name, value, config, true)
+	if err != nil {
+		return err
+	}
+	ctx.SetCookie(name, encoded, config)
+	return nil
+}
+
+// EncryptedCookie returns the value of the encrypted cookie, which is decrypted by trying all secret keys in order.
+func (ctx *Context) EncryptedCookie(name string) (string, error) {
+	ctx.checkReleased()
+	return ctx.decodeCookie(name, true)
+}
+
+// encodeCookie encodes the value by the first secret key.
+//
+// The format is base64(expires | value | HMAC(name | expires | value)) for signed cookies,
+// and base64(expires | nonce | AES-GCM(value)) for encrypted cookies, which authenticates the name
+// and expires as additional data. The expiration time is in Unix seconds, zero means no expiration.
+func (ctx *Context) encodeCookie(name, value string, config *CookieConfig, encrypt bool) (string, error) {
+	if len(ctx.router.cookieKeys) == 0 {
+		return "", ErrNoSecretKey
+	}
+	key := ctx.router.cookieKeys[0]
+
+	b := make([]byte, 8, 8+len(value)+sha256.Size)
+	if config != nil && config.MaxAge > 0 {
+		binary.BigEndian.PutUint64(b, uint64(time.Now().Add(time.Duration(config.MaxAge)*time.Second).Unix()))
+	}
+	if encrypt {
+		nonce := make([]byte, key.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		b = append(b, nonce...)
+		b = key.aead.Seal(b, nonce, []byte(value), cookieData(name, b[:8]))
+	} else {
+		b = append(b, value...)
+		b = append(b, cookieSignature(key.hash, name, b)...)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCookie decodes the cookie by trying all secret keys in order.
+func (ctx *Context) decodeCookie(name string, encrypted bool) (string, error) {
+	if len(ctx.router.cookieKeys) == 0 {
+		return "", ErrNoSecretKey
+	}
+	value := ctx.Request.Header.Cookie(name)
+	if len(value) == 0 {
+		return "", ErrCookieMissing
+	}
+	b, err := base64.RawURLEncoding.DecodeString(string(value))
+	if err != nil || len(b) < 8 {
+		return "", ErrInvalidCookie
+	}
+
+	for _, key := range ctx.router.cookieKeys {
+		var data []byte
+		if encrypted {
+			if len(b) < 8+key.aead.NonceSize() {
+				return "", ErrInvalidCookie
+			}
+			nonce := b[8 : 8+key.aead.NonceSize()]
+			if data, err = key.aead.Open(nil, nonce, b[8+len(nonce):], cookieData(name, b[:8])); err != nil {
+				continue
+			}
+		} else {
+			if len(b) < 8+sha256.Size {
+				return "", ErrInvalidCookie
+			}
+			message, mac := b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+			if !hmac.Equal(mac, cookieSignature(key.hash, name, message)) {
+				continue
+			}
+			data = message[8:]
+		}
+
+		if expires := int64(binary.BigEndian.Uint64(b)); expires != 0 && time.Now().Unix() >= expires {
+			return "", ErrCookieExpired
+		}
+		return string(data), nil
+	}
+	return "", ErrInvalidCookie
+}
+
+// cookieData returns the additional data of encrypted cookie.
+func cookieData(name string, expires []byte) []byte {
+	return append(append([]byte(name), 0), expires...)
+}
+
+// cookieSignature returns the HMAC-SHA256 of the cookie name and message.
+func cookieSignature(key []byte, name string, message []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write(message)
+	return h.Sum(nil)
+}
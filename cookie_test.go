@@ -0,0 +1,117 @@
+package clevergo
+
+import (
+	"errors"
+	"github.com/valyala/fasthttp"
+	"strings"
+	"testing"
+)
+
+// newCookieContext returns a context of the request with the cookie.
+func newCookieContext(r *Router, cookie string) *Context {
+	ctx := &fasthttp.RequestCtx{}
+	if cookie != "" {
+		ctx.Request.Header.Set("Cookie", cookie)
+	}
+	return NewContext(r, ctx, nil)
+}
+
+// responseCookie returns the response cookie in the form of "name=value".
+func responseCookie(ctx *Context, name string) string {
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+	cookie.SetKey(name)
+	ctx.Response.Header.Cookie(cookie)
+	return name + "=" + string(cookie.Value())
+}
+
+func TestContext_SetCookie(t *testing.T) {
+	r := NewRouter()
+	ctx := newCookieContext(r, "theme=dark")
+	defer ctx.Close()
+	if ctx.Cookie("theme") != "dark" || ctx.Cookie("missing") != "" {
+		t.Errorf("unexpected cookies %q", ctx.Request.Header.Peek("Cookie"))
+	}
+
+	ctx.SetCookie("theme", "light", nil)
+	config := NewCookieConfig()
+	config.SameSite = fasthttp.CookieSameSiteNoneMode
+	config.MaxAge = 3600
+	ctx.SetCookie("embed", "1", config)
+	ctx.DeleteCookie("old", nil)
+
+	tests := []struct {
+		name       string
+		attributes []string
+		absent     []string
+	}{
+		{"theme", []string{"theme=light", "path=/", "HttpOnly", "SameSite=Lax"}, []string{"secure", "max-age"}},
+		{"embed", []string{"max-age=3600", "secure", "SameSite=None"}, nil},
+		{"old", []string{"old=", "expires=Tue, 10 Nov 2009 23:00:00 GMT"}, nil},
+	}
+	for _, test := range tests {
+		cookie := string(ctx.Response.Header.PeekCookie(test.name))
+		for _, attribute := range test.attributes {
+			if !strings.Contains(cookie, attribute) {
+				t.Errorf("cookie %q doesn't contain %q", cookie, attribute)
+			}
+		}
+		for _, attribute := range test.absent {
+			if strings.Contains(cookie, attribute) {
+				t.Errorf("cookie %q contains %q", cookie, attribute)
+			}
+		}
+	}
+}
+
+func TestContext_SecureCookies(t *testing.T) {
+	oldKey, newKey := []byte("old secret"), []byte("new secret")
+	r := NewRouter()
+
+	ctx := newCookieContext(r, "")
+	if err := ctx.SetSignedCookie("user", "1", nil); !errors.Is(err, ErrNoSecretKey) {
+		t.Errorf("unexpected error %v", err)
+	}
+	ctx.Close()
+
+	r.SetSecretKeys(oldKey)
+	ctx = newCookieContext(r, "")
+	ctx.SetSignedCookie("user", "alice", nil)
+	ctx.SetEncryptedCookie("token", "secret", nil)
+	signed, encrypted := responseCookie(ctx, "user"), responseCookie(ctx, "token")
+	ctx.Close()
+	if strings.Contains(encrypted, "secret") {
+		t.Errorf("the encrypted cookie should not contain the plain value: %q", encrypted)
+	}
+
+	// The cookies encoded by the old key are still valid after rotation.
+	r.SetSecretKeys(newKey, oldKey)
+	ctx = newCookieContext(r, signed+"; "+encrypted)
+	if value, err := ctx.SignedCookie("user"); err != nil || value != "alice" {
+		t.Errorf("unexpected signed cookie %q, %v", value, err)
+	}
+	if value, err := ctx.EncryptedCookie("token"); err != nil || value != "secret" {
+		t.Errorf("unexpected encrypted cookie %q, %v", value, err)
+	}
+	// The signed and encrypted cookies are not interchangeable, and the cookie can not be renamed.
+	if _, err := ctx.EncryptedCookie("user"); !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := ctx.SignedCookie("missing"); !errors.Is(err, ErrCookieMissing) {
+		t.Errorf("unexpected error %v", err)
+	}
+	ctx.Close()
+
+	ctx = newCookieContext(r, "admin="+strings.TrimPrefix(signed, "user="))
+	if _, err := ctx.SignedCookie("admin"); !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("unexpected error %v", err)
+	}
+	ctx.Close()
+
+	r.SetSecretKeys(newKey)
+	ctx = newCookieContext(r, signed)
+	if _, err := ctx.SignedCookie("user"); !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("unexpected error %v", err)
+	}
+	ctx.Close()
+}
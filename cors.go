@@ -0,0 +1,184 @@
+package clevergo
+
+import (
+	"github.com/valyala/fasthttp"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CORSConfig for CORSMiddleware.
+type CORSConfig struct {
+	// Allowed origins, such as "https://example.com", "https://*.example.com" or "*".
+	AllowedOrigins []string
+	// Allowed origins' regular expressions.
+	AllowedOriginPatterns []*regexp.Regexp
+	// AllowOriginFunc reports whether the origin is allowed, it takes precedence over
+	// AllowedOrigins and AllowedOriginPatterns if it is non-nil.
+	AllowOriginFunc func(origin string) bool
+	// Allowed methods, empty means the methods registered for the request path.
+	AllowedMethods []string
+	// Allowed headers, empty means the headers requested by Access-Control-Request-Headers.
+	AllowedHeaders []string
+	// Headers which are safe to expose to the client.
+	ExposedHeaders []string
+	// Whether the request can include credentials.
+	AllowCredentials bool
+	// How long in seconds the preflight result can be cached, zero means no Access-Control-Max-Age header.
+	MaxAge int
+}
+
+// NewCORSConfig returns default CORS configuration that allows all origins.
+func NewCORSConfig() *CORSConfig {
+	return &CORSConfig{
+		AllowedOrigins: []string{"*"},
+	}
+}
+
+// CORSMiddleware handles the Cross-Origin Resource Sharing requests.
+//
+// The preflight requests are answered by the middleware directly,
+// and the other requests will be passed to the next handler.
+type CORSMiddleware struct {
+	config *CORSConfig
+}
+
+// NewCORSMiddleware returns a CORSMiddleware's instance.
+//
+// The default configuration will be used if config is nil.
+// It panics if the wildcard origin "*" is allowed with credentials, since any website could read
+// the responses with the user's credentials, the explicit origins, patterns or AllowOriginFunc
+// should be used instead.
+func NewCORSMiddleware(config *CORSConfig) *CORSMiddleware {
+	if config == nil {
+		config = NewCORSConfig()
+	}
+	if config.AllowCredentials && config.AllowOriginFunc == nil && containsFold(config.AllowedOrigins, "*") {
+		panic("clevergo: CORS wildcard origin can not be used with credentials")
+	}
+	return &CORSMiddleware{config: config}
+}
+
+// Handle implemented Middleware Interface.
+func (m *CORSMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		origin := string(ctx.Request.Header.Peek("Origin"))
+		if origin == "" {
+			next.Handle(ctx)
+			return
+		}
+
+		requestMethod := string(ctx.Request.Header.Peek("Access-Control-Request-Method"))
+		if string(ctx.Method()) == "OPTIONS" && requestMethod != "" {
+			m.handlePreflight(ctx, origin, requestMethod)
+			return
+		}
+
+		next.Handle(ctx)
+
+		// Set headers after handling, since the handler may reset the response, such as by Context.Error.
+		// The middlewares which write the response headers or cookies follow the same order.
+		addVary(ctx, "Origin")
+		if !m.isOriginAllowed(origin) {
+			return
+		}
+		m.setAllowOrigin(ctx, origin)
+		if len(m.config.ExposedHeaders) > 0 {
+			ctx.Response.Header.Set("Access-Control-Expose-Headers", strings.Join(m.config.ExposedHeaders, ", "))
+		}
+	})
+}
+
+// handlePreflight answers the preflight request.
+func (m *CORSMiddleware) handlePreflight(ctx *Context, origin, requestMethod string) {
+	addVary(ctx, "Origin")
+	addVary(ctx, "Access-Control-Request-Method")
+	addVary(ctx, "Access-Control-Request-Headers")
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+
+	if !m.isOriginAllowed(origin) {
+		return
+	}
+
+	methods := m.config.AllowedMethods
+	if len(methods) == 0 {
+		methods = ctx.router.AllowedMethods(string(ctx.Path()))
+	}
+	if !containsFold(methods, requestMethod) {
+		return
+	}
+
+	m.setAllowOrigin(ctx, origin)
+	ctx.Response.Header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+	if len(m.config.AllowedHeaders) > 0 {
+		ctx.Response.Header.Set("Access-Control-Allow-Headers", strings.Join(m.config.AllowedHeaders, ", "))
+	} else if headers := ctx.Request.Header.Peek("Access-Control-Request-Headers"); len(headers) > 0 {
+		ctx.Response.Header.SetBytesV("Access-Control-Allow-Headers", headers)
+	}
+
+	if m.config.MaxAge > 0 {
+		ctx.Response.Header.Set("Access-Control-Max-Age", strconv.Itoa(m.config.MaxAge))
+	}
+}
+
+// setAllowOrigin sets Access-Control-Allow-Origin and Access-Control-Allow-Credentials.
+func (m *CORSMiddleware) setAllowOrigin(ctx *Context, origin string) {
+	// The wildcard is never combined with credentials, see NewCORSMiddleware.
+	if m.config.AllowOriginFunc == nil && containsFold(m.config.AllowedOrigins, "*") {
+		ctx.Response.Header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+
+	ctx.Response.Header.Set("Access-Control-Allow-Origin", origin)
+	if m.config.AllowCredentials {
+		ctx.Response.Header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// isOriginAllowed reports whether the origin is allowed.
+func (m *CORSMiddleware) isOriginAllowed(origin string) bool {
+	if m.config.AllowOriginFunc != nil {
+		return m.config.AllowOriginFunc(origin)
+	}
+
+	origin = strings.ToLower(origin)
+	for _, allowed := range m.config.AllowedOrigins {
+		if matchOrigin(strings.ToLower(allowed), origin) {
+			return true
+		}
+	}
+
+	for _, pattern := range m.config.AllowedOriginPatterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchOrigin reports whether the origin matches the allowed origin,
+// the allowed origin may contain a wildcard, such as "https://*.example.com".
+func matchOrigin(allowed, origin string) bool {
+	if allowed == "*" || allowed == origin {
+		return true
+	}
+
+	i := strings.IndexByte(allowed, '*')
+	if i < 0 {
+		return false
+	}
+	prefix, suffix := allowed[:i], allowed[i+1:]
+	return len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+// containsFold reports whether the value is in the list, case-insensitively.
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
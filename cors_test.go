@@ -0,0 +1,164 @@
+package clevergo
+
+import (
+	"regexp"
+	"testing"
+)
+
+func newCORSRouter(config *CORSConfig) *Router {
+	r := NewRouter()
+	r.AddMiddleware(NewCORSMiddleware(config))
+	r.GET("/users", HandlerFunc(func(ctx *Context) {
+		ctx.Text("users")
+	}))
+	r.PUT("/users", HandlerFunc(func(ctx *Context) {
+		ctx.Text("updated")
+	}))
+	r.GET("/error", HandlerFunc(func(ctx *Context) {
+		ctx.Error("error", 500)
+	}))
+	return r
+}
+
+func TestCORSMiddleware_Preflight(t *testing.T) {
+	config := &CORSConfig{
+		AllowedOrigins:   []string{"https://example.com", "https://*.example.org"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}
+	r := newCORSRouter(config)
+
+	resp := serve(t, r.Handler, "OPTIONS /users HTTP/1.1\r\nOrigin: https://example.com\r\nAccess-Control-Request-Method: PUT\r\nAccess-Control-Request-Headers: X-Token\r\n\r\n")
+	if resp.StatusCode() != 204 {
+		t.Errorf("Unexpected status code %d. Expected %d", resp.StatusCode(), 204)
+	}
+	headers := map[string]string{
+		"Access-Control-Allow-Origin":      "https://example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, HEAD, PUT, OPTIONS",
+		"Access-Control-Allow-Headers":     "X-Token",
+		"Access-Control-Max-Age":           "600",
+	}
+	for name, value := range headers {
+		if v := string(resp.Header.Peek(name)); v != value {
+			t.Errorf("Unexpected %s %q. Expected %q", name, v, value)
+		}
+	}
+
+	// Method is not registered.
+	resp = serve(t, r.Handler, "OPTIONS /users HTTP/1.1\r\nOrigin: https://example.com\r\nAccess-Control-Request-Method: DELETE\r\n\r\n")
+	if len(resp.Header.Peek("Access-Control-Allow-Origin")) > 0 {
+		t.Errorf("Unregistered method should not be allowed")
+	}
+
+	// Wildcard origin.
+	resp = serve(t, r.Handler, "OPTIONS /users HTTP/1.1\r\nOrigin: https://api.example.org\r\nAccess-Control-Request-Method: GET\r\n\r\n")
+	if v := string(resp.Header.Peek("Access-Control-Allow-Origin")); v != "https://api.example.org" {
+		t.Errorf("Unexpected Access-Control-Allow-Origin %q", v)
+	}
+
+	// Origin is not allowed.
+	resp = serve(t, r.Handler, "OPTIONS /users HTTP/1.1\r\nOrigin: https://evil.com\r\nAccess-Control-Request-Method: GET\r\n\r\n")
+	if len(resp.Header.Peek("Access-Control-Allow-Origin")) > 0 {
+		t.Errorf("Disallowed origin should not get CORS headers")
+	}
+
+	// Plain OPTIONS request is answered by the automatic OPTIONS handler.
+	resp = serve(t, r.Handler, "OPTIONS /users HTTP/1.1\r\n\r\n")
+	if v := string(resp.Header.Peek("Allow")); v != "GET, HEAD, PUT, OPTIONS" {
+		t.Errorf("Unexpected Allow %q", v)
+	}
+}
+
+func TestCORSMiddleware_Request(t *testing.T) {
+	r := newCORSRouter(&CORSConfig{
+		AllowedOrigins: []string{"*"},
+		ExposedHeaders: []string{"X-Total"},
+	})
+
+	resp := serve(t, r.Handler, "GET /users HTTP/1.1\r\nOrigin: https://example.com\r\n\r\n")
+	if string(resp.Body()) != "users" {
+		t.Errorf("Unexpected body %q", resp.Body())
+	}
+	if v := string(resp.Header.Peek("Access-Control-Allow-Origin")); v != "*" {
+		t.Errorf("Unexpected Access-Control-Allow-Origin %q", v)
+	}
+	if v := string(resp.Header.Peek("Access-Control-Expose-Headers")); v != "X-Total" {
+		t.Errorf("Unexpected Access-Control-Expose-Headers %q", v)
+	}
+	if v := string(resp.Header.Peek("Vary")); v != "Origin" {
+		t.Errorf("Unexpected Vary %q", v)
+	}
+
+	resp = serve(t, r.Handler, "GET /error HTTP/1.1\r\nOrigin: https://example.com\r\n\r\n")
+	if v := string(resp.Header.Peek("Access-Control-Allow-Origin")); v != "*" {
+		t.Errorf("CORS headers should survive the reset of response, got %q", v)
+	}
+
+	resp = serve(t, r.Handler, "GET /users HTTP/1.1\r\n\r\n")
+	if len(resp.Header.Peek("Access-Control-Allow-Origin")) > 0 {
+		t.Errorf("Same-origin request should not get CORS headers")
+	}
+}
+
+func TestCORSMiddleware_IsOriginAllowed(t *testing.T) {
+	m := NewCORSMiddleware(&CORSConfig{
+		AllowedOrigins:        []string{"https://example.com", "https://*.example.org"},
+		AllowedOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^https://[a-z]+\.test$`)},
+	})
+
+	tests := map[string]bool{
+		"https://example.com":     true,
+		"HTTPS://EXAMPLE.COM":     true,
+		"http://example.com":      false,
+		"https://a.example.org":   true,
+		"https://example.org":     false,
+		"https://evilexample.org": false,
+		"https://foo.test":        true,
+		"https://foo1.test":       false,
+	}
+	for origin, expected := range tests {
+		if allowed := m.isOriginAllowed(origin); allowed != expected {
+			t.Errorf("isOriginAllowed(%q) = %v, expect %v", origin, allowed, expected)
+		}
+	}
+
+	m = NewCORSMiddleware(&CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowOriginFunc: func(origin string) bool {
+			return origin == "https://callback.com"
+		},
+	})
+	if !m.isOriginAllowed("https://callback.com") || m.isOriginAllowed("https://example.com") {
+		t.Errorf("AllowOriginFunc should take precedence")
+	}
+}
+
+func TestCORSMiddleware_WildcardCredentials(t *testing.T) {
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected panic of wildcard origin with credentials")
+			}
+		}()
+		config := NewCORSConfig()
+		config.AllowCredentials = true
+		NewCORSMiddleware(config)
+	}()
+
+	// The origins allowed by callback are echoed with credentials.
+	r := newCORSRouter(&CORSConfig{
+		AllowOriginFunc: func(origin string) bool {
+			return origin == "https://example.com"
+		},
+		AllowCredentials: true,
+	})
+	resp := serve(t, r.Handler, "GET /users HTTP/1.1\r\nOrigin: https://example.com\r\n\r\n")
+	if string(resp.Header.Peek("Access-Control-Allow-Origin")) != "https://example.com" || string(resp.Header.Peek("Access-Control-Allow-Credentials")) != "true" {
+		t.Errorf("Unexpected headers %s", resp.Header.String())
+	}
+	resp = serve(t, r.Handler, "GET /users HTTP/1.1\r\nOrigin: https://evil.com\r\n\r\n")
+	if len(resp.Header.Peek("Access-Control-Allow-Origin")) > 0 || len(resp.Header.Peek("Access-Control-Allow-Credentials")) > 0 {
+		t.Errorf("Unexpected headers %s", resp.Header.String())
+	}
+}
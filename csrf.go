@@ -0,0 +1,258 @@
+package clevergo
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"html/template"
+	"time"
+)
+
+var (
+	// ErrCSRFTokenMissing means that the request doesn't contain a CSRF token.
+	ErrCSRFTokenMissing = errors.New("CSRF token missing")
+	// ErrCSRFTokenInvalid means that the request's CSRF token is invalid.
+	ErrCSRFTokenInvalid = errors.New("CSRF token invalid")
+)
+
+// csrfSessionKey is the session value's key of CSRF token.
+const csrfSessionKey = "_csrf"
+
+// CSRFConfig for CSRFMiddleware.
+type CSRFConfig struct {
+	// Whether to store the token in a cookie instead of session, known as double submit cookie.
+	DoubleSubmit bool
+	// Name of cookie which is used to store the token in double submit mode.
+	CookieName string
+	// Max age of cookie in seconds, zero means session cookie.
+	CookieMaxAge int
+	// Name of form field which contains the submitted token.
+	FormField string
+	// Name of header which contains the submitted token.
+	HeaderName string
+	// Length of token in bytes.
+	TokenLength int
+	// Routes which are exempted from checking, such as "/webhooks/:name".
+	Exempt []string
+	// ExemptFunc reports whether the request is exempted from checking.
+	ExemptFunc func(ctx *Context) bool
+}
+
+// NewCSRFConfig returns default CSRF configuration.
+func NewCSRFConfig() *CSRFConfig {
+	return &CSRFConfig{
+		DoubleSubmit: false,
+		CookieName:   "_csrf",
+		FormField:    "_csrf",
+		HeaderName:   "X-CSRF-Token",
+		TokenLength:  32,
+	}
+}
+
+// CSRFMiddleware protects the requests from Cross-Site Request Forgery.
+//
+// It issues a token per session (or per double submit cookie), and validates the token
+// submitted by the form field or header on the unsafe methods,
+// the invalid requests will be handled by Context.HandleError with status code 403.
+//
+// The token of the current request is available by Context.CSRFToken,
+// and by the "csrfToken" and "csrfField" template functions in Context.Render.
+type CSRFMiddleware struct {
+	config *CSRFConfig
+}
+
+// NewCSRFMiddleware returns a CSRFMiddleware's instance.
+//
+// The default configuration will be used if config is nil.
+func NewCSRFMiddleware(config *CSRFConfig) *CSRFMiddleware {
+	if config == nil {
+		config = NewCSRFConfig()
+	}
+	return &CSRFMiddleware{config: config}
+}
+
+// Handle implemented Middleware Interface.
+func (m *CSRFMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		token, err := m.getToken(ctx)
+		if err != nil {
+			ctx.Logger().Printf("CSRF: failed to load token: %s", err)
+		}
+		issued := false
+		if len(token) != m.config.TokenLength {
+			token = generateToken(m.config.TokenLength)
+			issued = true
+		}
+
+		masked := maskToken(token)
+		ctx.csrfToken = masked
+		ctx.SetTemplateFunc("csrfToken", func() string {
+			return masked
+		})
+		ctx.SetTemplateFunc("csrfField", func() template.HTML {
+			return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`, template.HTMLEscapeString(m.config.FormField), masked))
+		})
+
+		// Store token before handling, so that it would be saved by SessionMiddleware.
+		m.storeToken(ctx, token, issued)
+
+		if !isSafeMethod(string(ctx.Method())) && !m.isExempt(ctx) {
+			if err := m.verify(ctx, token); err != nil {
+				ctx.HandleError(fasthttp.StatusForbidden, err)
+				m.saveToken(ctx, token, issued)
+				return
+			}
+		}
+
+		next.Handle(ctx)
+
+		m.saveToken(ctx, token, issued)
+	})
+}
+
+// verify checks the submitted token.
+func (m *CSRFMiddleware) verify(ctx *Context, token []byte) error {
+	submitted := ctx.Request.Header.Peek(m.config.HeaderName)
+	if len(submitted) == 0 {
+		submitted = ctx.FormValue(m.config.FormField)
+	}
+	if len(submitted) == 0 {
+		return ErrCSRFTokenMissing
+	}
+
+	if unmasked := unmaskToken(string(submitted)); unmasked == nil || subtle.ConstantTimeCompare(unmasked, token) != 1 {
+		return ErrCSRFTokenInvalid
+	}
+	return nil
+}
+
+// isExempt reports whether the request is exempted from checking.
+func (m *CSRFMiddleware) isExempt(ctx *Context) bool {
+	if m.config.ExemptFunc != nil && m.config.ExemptFunc(ctx) {
+		return true
+	}
+
+	path := string(ctx.Path())
+	for _, route := range m.config.Exempt {
+		if matchPath(route, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// getToken returns the stored token.
+func (m *CSRFMiddleware) getToken(ctx *Context) ([]byte, error) {
+	var encoded string
+	if m.config.DoubleSubmit {
+		encoded = string(ctx.Request.Header.Cookie(m.config.CookieName))
+	} else {
+		session, err := ctx.GetSession()
+		if err != nil {
+			return nil, err
+		}
+		encoded, _ = session.Values[csrfSessionKey].(string)
+	}
+
+	if encoded == "" {
+		return nil, nil
+	}
+	return base64.RawURLEncoding.DecodeString(encoded)
+}
+
+// storeToken stores the newly issued token in session.
+func (m *CSRFMiddleware) storeToken(ctx *Context, token []byte, issued bool) {
+	if !issued || m.config.DoubleSubmit {
+		return
+	}
+
+	session, err := ctx.GetSession()
+	if err != nil {
+		ctx.Logger().Printf("CSRF: failed to store token: %s", err)
+		return
+	}
+	session.Values[csrfSessionKey] = base64.RawURLEncoding.EncodeToString(token)
+}
+
+// saveToken saves the newly issued token to double submit cookie or session.
+func (m *CSRFMiddleware) saveToken(ctx *Context, token []byte, issued bool) {
+	if !issued {
+		return
+	}
+
+	if !m.config.DoubleSubmit {
+		// It does nothing if the session has been saved by SessionMiddleware.
+		if err := ctx.SaveSession(); err != nil {
+			ctx.Logger().Printf("CSRF: failed to save token: %s", err)
+		}
+		return
+	}
+
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+	cookie.SetKey(m.config.CookieName)
+	cookie.SetValue(base64.RawURLEncoding.EncodeToString(token))
+	cookie.SetPath("/")
+	cookie.SetHTTPOnly(true)
+	cookie.SetSecure(ctx.Scheme() == "https")
+	cookie.SetSameSite(fasthttp.CookieSameSiteLaxMode)
+	if m.config.CookieMaxAge > 0 {
+		cookie.SetMaxAge(m.config.CookieMaxAge)
+		cookie.SetExpire(time.Now().Add(time.Duration(m.config.CookieMaxAge) * time.Second))
+	}
+	ctx.Response.Header.SetCookie(cookie)
+}
+
+// CSRFToken returns the masked CSRF token of the current request,
+// it should be submitted by the form field or header.
+//
+// Returns an empty string if the CSRFMiddleware is not applied.
+func (ctx *Context) CSRFToken() string {
+	ctx.checkReleased()
+	return ctx.csrfToken
+}
+
+// isSafeMethod reports whether the method is safe, which is not protected by CSRFMiddleware.
+func isSafeMethod(method string) bool {
+	return method == "GET" || method == "HEAD" || method == "OPTIONS" || method == "TRACE"
+}
+
+// generateToken returns random bytes of length n.
+func generateToken(n int) []byte {
+	token := make([]byte, n)
+	if _, err := rand.Read(token); err != nil {
+		panic(err)
+	}
+	return token
+}
+
+// maskToken returns the token masked by a one-time pad, which is different on each request,
+// so that the token is not vulnerable to BREACH attack.
+func maskToken(token []byte) string {
+	pad := generateToken(len(token))
+	masked := make([]byte, len(token)*2)
+	copy(masked, pad)
+	for i := range token {
+		masked[len(token)+i] = pad[i] ^ token[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// unmaskToken returns the original token of the masked token,
+// returns nil if the masked token is malformed.
+func unmaskToken(masked string) []byte {
+	data, err := base64.RawURLEncoding.DecodeString(masked)
+	if err != nil || len(data) == 0 || len(data)%2 != 0 {
+		return nil
+	}
+
+	n := len(data) / 2
+	token := make([]byte, n)
+	for i := 0; i < n; i++ {
+		token[i] = data[i] ^ data[n+i]
+	}
+	return token
+}
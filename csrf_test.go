@@ -0,0 +1,163 @@
+package clevergo
+
+import (
+	"bytes"
+	"errors"
+	"github.com/clevergo/sessions"
+	"github.com/valyala/fasthttp"
+	"html/template"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testSessionStore is a session store keeps one session per name, for testing.
+type testSessionStore struct {
+	sessions map[string]*sessions.Session
+	saved    int
+}
+
+func newTestSessionStore() *testSessionStore {
+	return &testSessionStore{sessions: make(map[string]*sessions.Session)}
+}
+
+func (s *testSessionStore) Get(ctx *fasthttp.RequestCtx, name string) (*sessions.Session, error) {
+	if session, ok := s.sessions[name]; ok {
+		return session, nil
+	}
+	return s.New(ctx, name)
+}
+
+func (s *testSessionStore) New(ctx *fasthttp.RequestCtx, name string) (*sessions.Session, error) {
+	return sessions.NewSession(s, name), nil
+}
+
+func (s *testSessionStore) Save(ctx *fasthttp.RequestCtx, session *sessions.Session) error {
+	s.sessions[session.Name()] = session
+	s.saved++
+	return nil
+}
+
+func newCSRFRouter(config *CSRFConfig) *Router {
+	tpl := template.Must(template.New("form").Funcs(TemplateFuncs()).Parse(`<form>{{ csrfField }}</form>{{ csrfToken }}`))
+
+	r := NewRouter()
+	r.AddMiddleware(NewCSRFMiddleware(config))
+	r.GET("/form", HandlerFunc(func(ctx *Context) {
+		ctx.Render(tpl, nil)
+	}))
+	r.GET("/token", HandlerFunc(func(ctx *Context) {
+		ctx.Text(ctx.CSRFToken())
+	}))
+	r.POST("/form", HandlerFunc(func(ctx *Context) {
+		ctx.Text("OK")
+	}))
+	r.POST("/webhooks/:name", HandlerFunc(func(ctx *Context) {
+		ctx.Text("OK")
+	}))
+	return r
+}
+
+func TestCSRFMiddleware_DoubleSubmit(t *testing.T) {
+	config := NewCSRFConfig()
+	config.DoubleSubmit = true
+	config.Exempt = []string{"/webhooks/:name"}
+	r := newCSRFRouter(config)
+
+	resp := serve(t, r.Handler, "GET /token HTTP/1.1\r\n\r\n")
+	token := string(resp.Body())
+	cookie := fasthttp.AcquireCookie()
+	cookie.SetKey(config.CookieName)
+	if !resp.Header.Cookie(cookie) {
+		t.Fatalf("Expected CSRF cookie")
+	}
+	if !cookie.HTTPOnly() || cookie.SameSite() != fasthttp.CookieSameSiteLaxMode {
+		t.Errorf("Unexpected cookie attributes %s", cookie)
+	}
+	cookieHeader := "Cookie: " + config.CookieName + "=" + string(cookie.Value()) + "\r\n"
+
+	// The token is masked differently on each request.
+	resp = serve(t, r.Handler, "GET /token HTTP/1.1\r\n"+cookieHeader+"\r\n")
+	if string(resp.Body()) == token {
+		t.Errorf("The token should be masked differently on each request")
+	}
+	if len(resp.Header.PeekCookie(config.CookieName)) > 0 {
+		t.Errorf("The existing token should not be issued again")
+	}
+
+	tests := []struct {
+		request string
+		code    int
+	}{
+		{"POST /form HTTP/1.1\r\n" + cookieHeader + "X-CSRF-Token: " + token + "\r\n\r\n", 200},
+		{"POST /form HTTP/1.1\r\n" + cookieHeader + "Content-Type: application/x-www-form-urlencoded\r\nContent-Length: " + strconv.Itoa(len("_csrf="+url.QueryEscape(token))) + "\r\n\r\n_csrf=" + url.QueryEscape(token), 200},
+		{"POST /form HTTP/1.1\r\n" + cookieHeader + "\r\n", 403},
+		{"POST /form HTTP/1.1\r\n" + cookieHeader + "X-CSRF-Token: " + maskToken([]byte("invalid")) + "\r\n\r\n", 403},
+		{"POST /form HTTP/1.1\r\nX-CSRF-Token: " + token + "\r\n\r\n", 403},
+		{"POST /webhooks/github HTTP/1.1\r\n\r\n", 200},
+	}
+	for i, test := range tests {
+		resp = serve(t, r.Handler, test.request)
+		if resp.StatusCode() != test.code {
+			t.Errorf("%d: unexpected status code %d. Expected %d", i, resp.StatusCode(), test.code)
+		}
+	}
+}
+
+func TestCSRFMiddleware_Session(t *testing.T) {
+	store := newTestSessionStore()
+	r := newCSRFRouter(nil)
+	r.SetSessionStore(store)
+
+	var handled error
+	r.SetErrorHandler(func(ctx *Context, code int, err error) {
+		handled = err
+		ctx.Error("Forbidden by handler", code)
+	})
+
+	resp := serve(t, r.Handler, "GET /form HTTP/1.1\r\n\r\n")
+	body := string(resp.Body())
+	if !strings.HasPrefix(body, `<form><input type="hidden" name="_csrf" value="`) {
+		t.Fatalf("Unexpected form %q", body)
+	}
+	token := body[strings.LastIndex(body, ">")+1:]
+	if !strings.Contains(body, `value="`+token+`"`) {
+		t.Errorf("csrfField and csrfToken should use the same token: %q", body)
+	}
+	if store.saved != 1 {
+		t.Errorf("Unexpected saved count %d", store.saved)
+	}
+
+	resp = serve(t, r.Handler, "POST /form HTTP/1.1\r\nX-CSRF-Token: "+token+"\r\n\r\n")
+	if resp.StatusCode() != 200 {
+		t.Errorf("Unexpected status code %d. Expected %d", resp.StatusCode(), 200)
+	}
+	if store.saved != 1 {
+		t.Errorf("The existing token should not be saved again")
+	}
+
+	resp = serve(t, r.Handler, "POST /form HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 403 || !bytes.Equal(resp.Body(), []byte("Forbidden by handler")) {
+		t.Errorf("Unexpected response %d %q", resp.StatusCode(), resp.Body())
+	}
+	if !errors.Is(handled, ErrCSRFTokenMissing) {
+		t.Errorf("Unexpected error %v", handled)
+	}
+}
+
+func TestMaskToken(t *testing.T) {
+	token := generateToken(32)
+	masked := maskToken(token)
+	if masked == maskToken(token) {
+		t.Errorf("maskToken should return different values")
+	}
+	if !bytes.Equal(unmaskToken(masked), token) {
+		t.Errorf("unmaskToken(maskToken(token)) should equal token")
+	}
+	for _, v := range []string{"", "!", "YWJj"} {
+		if unmaskToken(v) != nil {
+			t.Errorf("unmaskToken(%q) should be nil", v)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package clevergo
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// debugMode indicates whether the debug mode is enabled.
+var debugMode bool
+
+// SetDebug enables or disables the debug mode, it should be called before serving.
+//
+// In debug mode, the closed contexts are poisoned instead of being reused, and using them panics
+// with the stacks of acquiring and releasing, which helps to find the handlers that retain
+// the context after the request is finished, such as in goroutines. Use Context.Copy instead.
+//
+// All of the methods of Context check it, while the methods of the embedded fasthttp.RequestCtx,
+// such as Path, panic with a nil pointer dereference, since the request is detached.
+func SetDebug(enabled bool) {
+	debugMode = enabled
+}
+
+// IsDebug reports whether the debug mode is enabled.
+func IsDebug() bool {
+	return debugMode
+}
+
+// poison marks the context as released.
+func (ctx *Context) poison(acquired []byte) {
+	ctx.acquiredStack = acquired
+	ctx.releasedStack = debug.Stack()
+}
+
+// checkReleased panics if the context has been released in debug mode.
+func (ctx *Context) checkReleased() {
+	if ctx.releasedStack != nil {
+		panic(fmt.Sprintf("clevergo: use of released Context\n\nacquired at:\n%s\nreleased at:\n%s", ctx.acquiredStack, ctx.releasedStack))
+	}
+}
@@ -0,0 +1,135 @@
+package clevergo
+
+import (
+	"bytes"
+	"context"
+	"github.com/clevergo/sessions"
+	"github.com/valyala/fasthttp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContext_Close(t *testing.T) {
+	ctx := NewContext(NewRouter(), nil, nil)
+	ctx.SetPrincipal("foo")
+	ctx.route = "/"
+	ctx.csrfToken = "token"
+	Set(ctx, "foo", "bar")
+	c := ctx.Context()
+
+	ctx.Close()
+	if ctx.router != nil || ctx.principal != nil || ctx.route != "" || ctx.csrfToken != "" || len(ctx.values) != 0 || ctx.stdContext != nil {
+		t.Errorf("the context is not reset: %+v", ctx)
+	}
+	if c.Err() == nil {
+		t.Error("expected the context.Context to be canceled")
+	}
+}
+
+func TestSetDebug(t *testing.T) {
+	SetDebug(true)
+	defer SetDebug(false)
+	if !IsDebug() {
+		t.Fatal("expected debug mode")
+	}
+
+	var retained, copied *Context
+	r := NewRouter()
+	r.GET("/users/:id", HandlerFunc(func(ctx *Context) {
+		ctx.SetPrincipal("foo")
+		Set(ctx, "tenant", 1)
+		ctx.WithValue(testContextKey{}, "bar")
+		retained = ctx
+		copied = ctx.Copy()
+		ctx.Text("OK")
+	}))
+	serve(t, r.Handler, "GET /users/1 HTTP/1.1\r\n\r\n")
+
+	// The copy is still usable.
+	if copied.Params.ByName("id") != "1" || copied.Principal() != "foo" || string(copied.Path()) != "/users/1" {
+		t.Errorf("unexpected copy %+v", copied)
+	}
+	if tenant, _ := Get[int](copied, "tenant"); tenant != 1 {
+		t.Errorf("unexpected tenant %d", tenant)
+	}
+	if c := copied.Context(); c.Err() != nil || c.Value(testContextKey{}) != "bar" {
+		t.Errorf("unexpected context.Context %v, %v", c.Err(), c.Value(testContextKey{}))
+	}
+
+	// The released context panics.
+	defer func() {
+		msg, _ := recover().(string)
+		if !strings.Contains(msg, "use of released Context") || !strings.Contains(msg, "acquired at:") || !strings.Contains(msg, "released at:") {
+			t.Errorf("unexpected panic %q", msg)
+		}
+	}()
+	retained.Text("use after close")
+}
+
+func TestContext_UseAfterClose(t *testing.T) {
+	SetDebug(true)
+	defer SetDebug(false)
+
+	tests := map[string]func(ctx *Context){
+		"params":   func(ctx *Context) { ctx.Param("id") },
+		"route":    func(ctx *Context) { ctx.Route() },
+		"input":    func(ctx *Context) { ctx.Query("q") },
+		"form":     func(ctx *Context) { ctx.FormInt("page", 1) },
+		"header":   func(ctx *Context) { ctx.Header("Accept") },
+		"cookie":   func(ctx *Context) { ctx.Cookie("foo") },
+		"redirect": func(ctx *Context) { ctx.Redirect(302, "/") },
+		"file":     func(ctx *Context) { ctx.ServeContent(bytes.NewReader(nil), "a.txt", time.Time{}) },
+		"session":  func(ctx *Context) { ctx.SaveSession() },
+		"csrf":     func(ctx *Context) { ctx.CSRFToken() },
+		"auth":     func(ctx *Context) { ctx.JWTClaims() },
+		"authz":    func(ctx *Context) { ctx.Authorize(Requirement{}) },
+		"cache":    func(ctx *Context) { ctx.NotModified("foo", time.Time{}) },
+		"proxy":    func(ctx *Context) { ctx.RealIP() },
+		"upload":   func(ctx *Context) { ctx.FormFile("file") },
+		"response": func(ctx *Context) { ctx.JSONWithCode(200, nil) },
+		"set":      func(ctx *Context) { Set(ctx, "foo", "bar") },
+		"get":      func(ctx *Context) { Get[string](ctx, "foo") },
+		"delete":   func(ctx *Context) { Delete(ctx, "foo") },
+	}
+	for name, fn := range tests {
+		ctx := NewContext(NewRouter(), nil, nil)
+		ctx.Close()
+		func() {
+			defer func() {
+				if msg, _ := recover().(string); !strings.Contains(msg, "use of released Context") {
+					t.Errorf("%s: unexpected panic %q", name, msg)
+				}
+			}()
+			fn(ctx)
+		}()
+	}
+}
+
+func TestContext_CopySession(t *testing.T) {
+	req := &fasthttp.RequestCtx{}
+	req.Init(&fasthttp.Request{}, nil, nil)
+	ctx := NewContext(NewRouter(), req, nil)
+	ctx.Session = &sessions.Session{Values: map[interface{}]interface{}{"foo": "bar"}}
+	c := ctx.Copy()
+	c.Session.Values["foo"] = "baz"
+	if ctx.Session.Values["foo"] != "bar" {
+		t.Errorf("the session of copy should not affect the request")
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	done := make(chan struct{})
+	c, cancel := context.WithCancel(requestContext{done: done})
+	defer cancel()
+	if c.Err() != nil || c.Value("foo") != nil {
+		t.Fatalf("unexpected context %v", c.Err())
+	}
+	// The server is shutting down.
+	close(done)
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the context.Context to be canceled")
+	}
+}
@@ -0,0 +1,60 @@
+package clevergo
+
+import (
+	"crypto/tls"
+	"github.com/valyala/fasthttp"
+	"syscall"
+	"time"
+)
+
+// disconnectPollInterval is the interval of checking whether the client has closed the connection.
+var disconnectPollInterval = 100 * time.Millisecond
+
+// watchDisconnect cancels the Context.Context when the client closes the connection.
+//
+// The connection is not read by fasthttp while the request is being handled, so that it is checked
+// periodically by peeking the socket, which doesn't consume the pipelined requests.
+// It does nothing if the connection is not a socket, such as the in-memory connections in tests.
+func (ctx *Context) watchDisconnect() {
+	conn := socketConn(ctx.RequestCtx)
+	if conn == nil {
+		return
+	}
+
+	done, cancel := ctx.stdContext.Done(), ctx.cancel
+	go func() {
+		ticker := time.NewTicker(disconnectPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if isDisconnected(conn) {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+}
+
+// socketConn returns the raw socket of the request's connection, or nil if it is not a socket.
+func socketConn(ctx *fasthttp.RequestCtx) syscall.RawConn {
+	if ctx == nil {
+		return nil
+	}
+	conn := ctx.Conn()
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return nil
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	return rc
+}
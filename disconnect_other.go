@@ -0,0 +1,13 @@
+//go:build !unix
+
+package clevergo
+
+import (
+	"syscall"
+)
+
+// isDisconnected reports whether the peer has closed the connection,
+// it is not supported on this platform.
+func isDisconnected(conn syscall.RawConn) bool {
+	return false
+}
@@ -0,0 +1,76 @@
+package clevergo
+
+import (
+	"bufio"
+	"context"
+	"github.com/valyala/fasthttp"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func serveTCP(t *testing.T, handler fasthttp.RequestHandler) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fasthttp.Server{Handler: handler}
+	go s.Serve(ln)
+	t.Cleanup(func() {
+		s.Shutdown()
+	})
+	return ln.Addr().String()
+}
+
+func TestContext_Disconnect(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the closed connection is not detected on windows")
+	}
+
+	canceled := make(chan error, 1)
+	r := NewRouter()
+	r.GET("/slow", HandlerFunc(func(ctx *Context) {
+		select {
+		case <-ctx.Context().Done():
+			canceled <- ctx.Context().Err()
+		case <-time.After(5 * time.Second):
+			canceled <- nil
+		}
+	}))
+	r.GET("/fast", HandlerFunc(func(ctx *Context) {
+		ctx.Context()
+		time.Sleep(2 * disconnectPollInterval)
+		ctx.Text("fast")
+	}))
+	addr := serveTCP(t, r.Handler)
+
+	// The pipelined requests are not consumed by checking the connection.
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /fast HTTP/1.1\r\nHost: example.com\r\n\r\nGET /fast HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	br := bufio.NewReader(conn)
+	for i := 0; i < 2; i++ {
+		resp := &fasthttp.Response{}
+		if err = resp.Read(br); err != nil {
+			t.Fatal(err)
+		}
+		if string(resp.Body()) != "fast" {
+			t.Errorf("%d: unexpected body %q", i, resp.Body())
+		}
+	}
+
+	conn, err = net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("GET /slow HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	time.Sleep(disconnectPollInterval)
+	conn.Close()
+	if err = <-canceled; err != context.Canceled {
+		t.Errorf("expected the context to be canceled by disconnect, got %v", err)
+	}
+}
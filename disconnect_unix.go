@@ -0,0 +1,27 @@
+//go:build unix
+
+package clevergo
+
+import (
+	"syscall"
+)
+
+// isDisconnected reports whether the peer has closed the connection.
+func isDisconnected(conn syscall.RawConn) bool {
+	disconnected := false
+	buf := make([]byte, 1)
+	err := conn.Control(func(fd uintptr) {
+		n, _, err := syscall.Recvfrom(int(fd), buf, syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		switch {
+		case err == syscall.EAGAIN || err == syscall.EWOULDBLOCK || err == syscall.EINTR:
+		case err != nil:
+			// Such as ECONNRESET.
+			disconnected = true
+		case n == 0:
+			// EOF.
+			disconnected = true
+		}
+	})
+	// The connection has been closed by the server.
+	return disconnected || err != nil
+}
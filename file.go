@@ -0,0 +1,130 @@
+package clevergo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// File responses the file, and handles the conditional and Range requests.
+//
+// The Content-Type is determined by the file's extension, or sniffed from the content.
+// The missing files and directories are handled by Context.HandleError with status code 404.
+func (ctx *Context) File(path string) {
+	ctx.checkReleased()
+	f, err := os.Open(path)
+	if err != nil {
+		ctx.handleFileError(err)
+		return
+	}
+	info, err := f.Stat()
+	if err == nil && info.IsDir() {
+		err = ErrNotFound
+	}
+	if err != nil {
+		f.Close()
+		ctx.handleFileError(err)
+		return
+	}
+
+	serveContent(ctx, mime.TypeByExtension(filepath.Ext(path)), info.ModTime(), info.Size(), f, modETag(info.ModTime(), info.Size()))
+}
+
+func (ctx *Context) handleFileError(err error) {
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, ErrNotFound) {
+		ctx.HandleError(fasthttp.StatusNotFound, err)
+		return
+	}
+	ctx.HandleError(fasthttp.StatusInternalServerError, err)
+}
+
+// Attachment responses the file as an attachment, which prompts the browser to download it with the filename.
+//
+// The filename defaults to the base name of path, the non-ASCII filename is encoded as RFC 6266.
+func (ctx *Context) Attachment(path, filename string) {
+	ctx.checkReleased()
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	ctx.Response.Header.Set("Content-Disposition", contentDisposition("attachment", filename))
+	ctx.File(path)
+}
+
+// Inline responses the file to be displayed in the browser, the filename is used when it is saved.
+func (ctx *Context) Inline(path, filename string) {
+	ctx.checkReleased()
+	if filename == "" {
+		filename = filepath.Base(path)
+	}
+	ctx.Response.Header.Set("Content-Disposition", contentDisposition("inline", filename))
+	ctx.File(path)
+}
+
+// ServeContent responses the content, and handles the conditional and Range requests like Context.File.
+//
+// The Content-Type is determined by the extension of name, or sniffed from the content if name has no
+// known extension. The modtime is used for the Last-Modified header and the conditional requests,
+// the zero time means unknown. The content will be closed after sending if it implements io.Closer.
+func (ctx *Context) ServeContent(content io.ReadSeeker, name string, modtime time.Time) {
+	ctx.checkReleased()
+	size, err := content.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = content.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		if closer, ok := content.(io.Closer); ok {
+			closer.Close()
+		}
+		ctx.HandleError(fasthttp.StatusInternalServerError, err)
+		return
+	}
+
+	etag := ""
+	if !isZeroTime(modtime) {
+		etag = modETag(modtime, size)
+	}
+	serveContent(ctx, mime.TypeByExtension(filepath.Ext(name)), modtime, size, content, etag)
+}
+
+// modETag returns the strong entity tag of the modification time and size.
+func modETag(modtime time.Time, size int64) string {
+	return fmt.Sprintf(`"%x-%x"`, modtime.UnixNano(), size)
+}
+
+// contentDisposition returns the Content-Disposition header of the filename, the non-ASCII filename is
+// encoded by the filename* parameter as RFC 6266, and the ASCII fallback is kept for the old clients.
+func contentDisposition(typ, filename string) string {
+	fallback := make([]byte, 0, len(filename))
+	for i := 0; i < len(filename); i++ {
+		c := filename[i]
+		if c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			c = '_'
+		}
+		fallback = append(fallback, c)
+	}
+	if string(fallback) == filename {
+		return typ + `; filename="` + filename + `"`
+	}
+
+	const hex = "0123456789ABCDEF"
+	var encoded strings.Builder
+	for i := 0; i < len(filename); i++ {
+		c := filename[i]
+		// attr-char of RFC 5987.
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			encoded.WriteByte(c)
+			continue
+		}
+		encoded.WriteByte('%')
+		encoded.WriteByte(hex[c>>4])
+		encoded.WriteByte(hex[c&0xf])
+	}
+	return typ + `; filename="` + string(fallback) + `"; filename*=UTF-8''` + encoded.String()
+}
@@ -0,0 +1,92 @@
+package clevergo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContext_File(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.csv")
+	if err := os.WriteFile(path, []byte("a,b\n1,2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, staticModTime, staticModTime)
+
+	r := NewRouter()
+	r.GET("/file", HandlerFunc(func(ctx *Context) {
+		ctx.File(path)
+	}))
+	r.GET("/dir", HandlerFunc(func(ctx *Context) {
+		ctx.File(dir)
+	}))
+	r.GET("/missing", HandlerFunc(func(ctx *Context) {
+		ctx.File(filepath.Join(dir, "missing"))
+	}))
+	r.GET("/attachment", HandlerFunc(func(ctx *Context) {
+		ctx.Attachment(path, "")
+	}))
+	r.GET("/inline", HandlerFunc(func(ctx *Context) {
+		ctx.Inline(path, "Résumé 2020.csv")
+	}))
+
+	resp := serve(t, r.Handler, "GET /file HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 200 || string(resp.Body()) != "a,b\n1,2\n" || !strings.HasPrefix(string(resp.Header.ContentType()), "text/csv") {
+		t.Errorf("unexpected response %d %q %q", resp.StatusCode(), resp.Header.ContentType(), resp.Body())
+	}
+	etag := string(resp.Header.Peek("ETag"))
+	if etag == "" || len(resp.Header.Peek("Content-Disposition")) > 0 {
+		t.Errorf("unexpected headers %s", resp.Header.String())
+	}
+
+	resp = serve(t, r.Handler, "GET /file HTTP/1.1\r\nIf-None-Match: "+etag+"\r\n\r\n")
+	if resp.StatusCode() != 304 {
+		t.Errorf("unexpected status code %d. Expected %d", resp.StatusCode(), 304)
+	}
+	resp = serve(t, r.Handler, "GET /file HTTP/1.1\r\nRange: bytes=4-\r\n\r\n")
+	if resp.StatusCode() != 206 || string(resp.Body()) != "1,2\n" {
+		t.Errorf("unexpected response %d %q", resp.StatusCode(), resp.Body())
+	}
+
+	for _, p := range []string{"/dir", "/missing"} {
+		if resp = serve(t, r.Handler, "GET "+p+" HTTP/1.1\r\n\r\n"); resp.StatusCode() != 404 {
+			t.Errorf("%s: unexpected status code %d. Expected %d", p, resp.StatusCode(), 404)
+		}
+	}
+
+	resp = serve(t, r.Handler, "GET /attachment HTTP/1.1\r\n\r\n")
+	if cd := string(resp.Header.Peek("Content-Disposition")); cd != `attachment; filename="report.csv"` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+	resp = serve(t, r.Handler, "GET /inline HTTP/1.1\r\n\r\n")
+	if cd := string(resp.Header.Peek("Content-Disposition")); cd != `inline; filename="R__sum__ 2020.csv"; filename*=UTF-8''R%C3%A9sum%C3%A9%202020.csv` {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+}
+
+func TestContext_ServeContent(t *testing.T) {
+	var modtime time.Time
+	r := NewRouter()
+	r.GET("/:name", HandlerFunc(func(ctx *Context) {
+		ctx.ServeContent(bytes.NewReader([]byte("<html><body>hello</body></html>")), ctx.Params.ByName("name"), modtime)
+	}))
+
+	resp := serve(t, r.Handler, "GET /page HTTP/1.1\r\n\r\n")
+	if string(resp.Header.ContentType()) != "text/html; charset=utf-8" || len(resp.Header.Peek("ETag")) > 0 {
+		t.Errorf("unexpected headers %s", resp.Header.String())
+	}
+	resp = serve(t, r.Handler, "GET /page.txt HTTP/1.1\r\nRange: bytes=-7\r\n\r\n")
+	if resp.StatusCode() != 206 || string(resp.Body()) != "</html>" || !strings.HasPrefix(string(resp.Header.ContentType()), "text/plain") {
+		t.Errorf("unexpected response %d %q %q", resp.StatusCode(), resp.Header.ContentType(), resp.Body())
+	}
+
+	modtime = staticModTime
+	resp = serve(t, r.Handler, "GET /page HTTP/1.1\r\nIf-Modified-Since: "+staticModTime.Format(time.RFC1123)+"\r\n\r\n")
+	if resp.StatusCode() != 304 {
+		t.Errorf("unexpected status code %d. Expected %d", resp.StatusCode(), 304)
+	}
+}
@@ -0,0 +1,78 @@
+package clevergo
+
+// RouteGroup registers the request handlers with the common path prefix and middlewares.
+//
+// The group's middlewares are applied after the router's middlewares.
+type RouteGroup struct {
+	router      *Router
+	prefix      string
+	middlewares []Middleware
+}
+
+// Group returns a RouteGroup of the path prefix.
+func (r *Router) Group(prefix string, middlewares ...Middleware) *RouteGroup {
+	return &RouteGroup{
+		router:      r,
+		prefix:      prefix,
+		middlewares: middlewares,
+	}
+}
+
+// Group returns a sub group, which inherits the prefix and middlewares.
+func (g *RouteGroup) Group(prefix string, middlewares ...Middleware) *RouteGroup {
+	return &RouteGroup{
+		router:      g.router,
+		prefix:      g.prefix + prefix,
+		middlewares: append(append([]Middleware{}, g.middlewares...), middlewares...),
+	}
+}
+
+// AddMiddleware add middleware, it affects the handlers registered afterwards only.
+func (g *RouteGroup) AddMiddleware(middleware Middleware) {
+	g.middlewares = append(g.middlewares, middleware)
+}
+
+// GET register GET request handler.
+func (g *RouteGroup) GET(path string, handler Handler) {
+	g.Handle("GET", path, handler)
+}
+
+// HEAD register HEAD request handler.
+func (g *RouteGroup) HEAD(path string, handler Handler) {
+	g.Handle("HEAD", path, handler)
+}
+
+// OPTIONS register OPTIONS request handler.
+func (g *RouteGroup) OPTIONS(path string, handler Handler) {
+	g.Handle("OPTIONS", path, handler)
+}
+
+// POST register POST request handler.
+func (g *RouteGroup) POST(path string, handler Handler) {
+	g.Handle("POST", path, handler)
+}
+
+// PUT register PUT request handler.
+func (g *RouteGroup) PUT(path string, handler Handler) {
+	g.Handle("PUT", path, handler)
+}
+
+// PATCH register PATCH request handler.
+func (g *RouteGroup) PATCH(path string, handler Handler) {
+	g.Handle("PATCH", path, handler)
+}
+
+// DELETE register DELETE request handler.
+func (g *RouteGroup) DELETE(path string, handler Handler) {
+	g.Handle("DELETE", path, handler)
+}
+
+// Handle register custom METHOD request handler.
+func (g *RouteGroup) Handle(method, path string, handler Handler) {
+	g.router.Handle(method, g.prefix+path, Chain(handler, g.middlewares...))
+}
+
+// Name names the routes of the path under the group's prefix, see Router.Name.
+func (g *RouteGroup) Name(name, path string) {
+	g.router.Name(name, g.prefix+path)
+}
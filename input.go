@@ -0,0 +1,238 @@
+package clevergo
+
+import (
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query returns the first value of query parameter, or the default value if it doesn't exist.
+func (ctx *Context) Query(name string, def ...string) string {
+	ctx.checkReleased()
+	if value := ctx.QueryArgs().Peek(name); value != nil {
+		return string(value)
+	}
+	return firstDefault(def)
+}
+
+// QueryValues returns all values of query parameter.
+func (ctx *Context) QueryValues(name string) []string {
+	ctx.checkReleased()
+	return argsValues(ctx.QueryArgs(), name)
+}
+
+// QueryInt returns the query parameter as int, or the default value if it doesn't exist or is invalid.
+func (ctx *Context) QueryInt(name string, def int) int {
+	ctx.checkReleased()
+	return parseInt(ctx.QueryArgs().Peek(name), def)
+}
+
+// QueryFloat returns the query parameter as float64, or the default value if it doesn't exist or is invalid.
+func (ctx *Context) QueryFloat(name string, def float64) float64 {
+	ctx.checkReleased()
+	return parseFloat(ctx.QueryArgs().Peek(name), def)
+}
+
+// QueryBool returns the query parameter as bool, or the default value if it doesn't exist or is invalid.
+//
+// The values "1", "t", "true", "on" and "yes" are true, "0", "f", "false", "off" and "no" are false, case-insensitively.
+func (ctx *Context) QueryBool(name string, def bool) bool {
+	ctx.checkReleased()
+	return parseBool(ctx.QueryArgs().Peek(name), def)
+}
+
+// QueryTime returns the query parameter as time in the layout,
+// or the default value if it doesn't exist or is invalid.
+func (ctx *Context) QueryTime(name, layout string, def time.Time) time.Time {
+	ctx.checkReleased()
+	return parseTime(ctx.QueryArgs().Peek(name), layout, def)
+}
+
+// Form returns the first value of form field in the request body, which is URL-encoded or multipart,
+// or the default value if it doesn't exist.
+func (ctx *Context) Form(name string, def ...string) string {
+	ctx.checkReleased()
+	if value := ctx.formValue(name); value != nil {
+		return string(value)
+	}
+	return firstDefault(def)
+}
+
+// FormValues returns all values of form field in the request body.
+func (ctx *Context) FormValues(name string) []string {
+	ctx.checkReleased()
+	if values := argsValues(ctx.PostArgs(), name); len(values) > 0 {
+		return values
+	}
+	if form, err := ctx.multipartForm(); err == nil {
+		return form.Value[name]
+	}
+	return nil
+}
+
+// FormInt returns the form field as int, or the default value if it doesn't exist or is invalid.
+func (ctx *Context) FormInt(name string, def int) int {
+	ctx.checkReleased()
+	return parseInt(ctx.formValue(name), def)
+}
+
+// FormFloat returns the form field as float64, or the default value if it doesn't exist or is invalid.
+func (ctx *Context) FormFloat(name string, def float64) float64 {
+	ctx.checkReleased()
+	return parseFloat(ctx.formValue(name), def)
+}
+
+// FormBool returns the form field as bool, or the default value if it doesn't exist or is invalid,
+// see QueryBool for the accepted values.
+func (ctx *Context) FormBool(name string, def bool) bool {
+	ctx.checkReleased()
+	return parseBool(ctx.formValue(name), def)
+}
+
+// FormTime returns the form field as time in the layout,
+// or the default value if it doesn't exist or is invalid.
+func (ctx *Context) FormTime(name, layout string, def time.Time) time.Time {
+	ctx.checkReleased()
+	return parseTime(ctx.formValue(name), layout, def)
+}
+
+// formValue returns the first value of form field, or nil if it doesn't exist.
+func (ctx *Context) formValue(name string) []byte {
+	if value := ctx.PostArgs().Peek(name); value != nil {
+		return value
+	}
+	if form, err := ctx.multipartForm(); err == nil {
+		if values := form.Value[name]; len(values) > 0 {
+			return []byte(values[0])
+		}
+	}
+	return nil
+}
+
+// Header returns the request header, or the default value if it doesn't exist.
+func (ctx *Context) Header(name string, def ...string) string {
+	ctx.checkReleased()
+	if value := ctx.Request.Header.Peek(name); value != nil {
+		return string(value)
+	}
+	return firstDefault(def)
+}
+
+// HeaderValues returns all values of request header, the comma-separated values are not split.
+func (ctx *Context) HeaderValues(name string) []string {
+	ctx.checkReleased()
+	var values []string
+	for _, value := range ctx.Request.Header.PeekAll(name) {
+		values = append(values, string(value))
+	}
+	return values
+}
+
+// HeaderInt returns the request header as int, or the default value if it doesn't exist or is invalid.
+func (ctx *Context) HeaderInt(name string, def int) int {
+	ctx.checkReleased()
+	return parseInt(ctx.Request.Header.Peek(name), def)
+}
+
+// HeaderBool returns the request header as bool, or the default value if it doesn't exist or is invalid,
+// see QueryBool for the accepted values.
+func (ctx *Context) HeaderBool(name string, def bool) bool {
+	ctx.checkReleased()
+	return parseBool(ctx.Request.Header.Peek(name), def)
+}
+
+// HeaderTime returns the request header as time in HTTP date format, such as If-Modified-Since,
+// or the default value if it doesn't exist or is invalid.
+func (ctx *Context) HeaderTime(name string, def time.Time) time.Time {
+	ctx.checkReleased()
+	value := ctx.Request.Header.Peek(name)
+	if value == nil {
+		return def
+	}
+	t, err := fasthttp.ParseHTTPDate(value)
+	if err != nil {
+		return def
+	}
+	return t
+}
+
+// Input returns the value of name, which is looked up in the following order:
+//
+//  1. route params
+//  2. query parameters
+//  3. form fields in the request body
+//
+// Returns the default value if it doesn't exist in any of them.
+func (ctx *Context) Input(name string, def ...string) string {
+	ctx.checkReleased()
+	if value := ctx.Param(name); value != "" {
+		return value
+	}
+	if value := ctx.QueryArgs().Peek(name); value != nil {
+		return string(value)
+	}
+	if value := ctx.formValue(name); value != nil {
+		return string(value)
+	}
+	return firstDefault(def)
+}
+
+// argsValues returns all values of the key.
+func argsValues(args *fasthttp.Args, key string) []string {
+	var values []string
+	for _, value := range args.PeekMulti(key) {
+		values = append(values, string(value))
+	}
+	return values
+}
+
+func firstDefault(def []string) string {
+	if len(def) > 0 {
+		return def[0]
+	}
+	return ""
+}
+
+func parseInt(value []byte, def int) int {
+	if value == nil {
+		return def
+	}
+	i, err := strconv.Atoi(string(value))
+	if err != nil {
+		return def
+	}
+	return i
+}
+
+func parseFloat(value []byte, def float64) float64 {
+	if value == nil {
+		return def
+	}
+	f, err := strconv.ParseFloat(string(value), 64)
+	if err != nil {
+		return def
+	}
+	return f
+}
+
+func parseBool(value []byte, def bool) bool {
+	switch strings.ToLower(string(value)) {
+	case "1", "t", "true", "on", "yes":
+		return true
+	case "0", "f", "false", "off", "no":
+		return false
+	}
+	return def
+}
+
+func parseTime(value []byte, layout string, def time.Time) time.Time {
+	if value == nil {
+		return def
+	}
+	t, err := time.Parse(layout, string(value))
+	if err != nil {
+		return def
+	}
+	return t
+}
@@ -0,0 +1,82 @@
+package clevergo
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestContext_Input(t *testing.T) {
+	var (
+		page, size    int
+		price         float64
+		remember, tos bool
+		since         time.Time
+		date          time.Time
+		tags          []string
+		ids           []string
+		accepts       []string
+		inputs        []string
+		missing       string
+	)
+	r := NewRouter()
+	r.POST("/posts/:id", HandlerFunc(func(ctx *Context) {
+		page, size = ctx.QueryInt("page", 1), ctx.QueryInt("size", 20)
+		price = ctx.FormFloat("price", 0)
+		remember, tos = ctx.FormBool("remember", false), ctx.QueryBool("tos", true)
+		since = ctx.HeaderTime("If-Modified-Since", time.Time{})
+		date = ctx.QueryTime("date", "2006-01-02", time.Time{})
+		tags, ids, accepts = ctx.QueryValues("tag"), ctx.FormValues("id"), ctx.HeaderValues("Accept")
+		inputs = []string{ctx.Input("id"), ctx.Input("page"), ctx.Input("price"), ctx.Input("none", "def")}
+		missing = ctx.Form("none")
+	}))
+
+	body := "price=9.5&remember=on&id=1&id=2"
+	serve(t, r.Handler, "POST /posts/42?page=3&size=x&tos=maybe&tag=go&tag=web&date=2020-02-29&id=7 HTTP/1.1\r\n"+
+		"If-Modified-Since: Sat, 29 Feb 2020 10:00:00 GMT\r\nAccept: text/html\r\nAccept: */*\r\n"+
+		"Content-Type: application/x-www-form-urlencoded\r\nContent-Length: "+strconv.Itoa(len(body))+"\r\n\r\n"+body)
+
+	if page != 3 || size != 20 {
+		t.Errorf("unexpected page %d and size %d", page, size)
+	}
+	if price != 9.5 || !remember || !tos {
+		t.Errorf("unexpected price %v, remember %v and tos %v", price, remember, tos)
+	}
+	if !since.Equal(time.Date(2020, 2, 29, 10, 0, 0, 0, time.UTC)) || !date.Equal(time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected since %s and date %s", since, date)
+	}
+	if !reflect.DeepEqual(tags, []string{"go", "web"}) || !reflect.DeepEqual(ids, []string{"1", "2"}) ||
+		!reflect.DeepEqual(accepts, []string{"text/html", "*/*"}) {
+		t.Errorf("unexpected values %v, %v and %v", tags, ids, accepts)
+	}
+	// Params take precedence over query, and query over form.
+	if !reflect.DeepEqual(inputs, []string{"42", "3", "9.5", "def"}) {
+		t.Errorf("unexpected inputs %v", inputs)
+	}
+	if missing != "" {
+		t.Errorf("unexpected form value %q", missing)
+	}
+}
+
+func TestContext_FormMultipart(t *testing.T) {
+	var (
+		name   string
+		age    int
+		values []string
+	)
+	r := NewRouter()
+	r.POST("/", HandlerFunc(func(ctx *Context) {
+		name, age, values = ctx.Form("name"), ctx.FormInt("age", 0), ctx.FormValues("tag")
+	}))
+
+	body := "--B\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\nfoo\r\n" +
+		"--B\r\nContent-Disposition: form-data; name=\"age\"\r\n\r\n18\r\n" +
+		"--B\r\nContent-Disposition: form-data; name=\"tag\"\r\n\r\na\r\n" +
+		"--B\r\nContent-Disposition: form-data; name=\"tag\"\r\n\r\nb\r\n--B--\r\n"
+	serve(t, r.Handler, "POST / HTTP/1.1\r\nContent-Type: multipart/form-data; boundary=B\r\nContent-Length: "+
+		strconv.Itoa(len(body))+"\r\n\r\n"+body)
+	if name != "foo" || age != 18 || !reflect.DeepEqual(values, []string{"a", "b"}) {
+		t.Errorf("unexpected form %q, %d, %v", name, age, values)
+	}
+}
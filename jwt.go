@@ -0,0 +1,88 @@
+package clevergo
+
+import (
+	"github.com/headwindfly/clevergo/jwt"
+	"github.com/valyala/fasthttp"
+	"strconv"
+)
+
+// JWTConfig for JWTMiddleware.
+type JWTConfig struct {
+	Realm      string            // Realm of WWW-Authenticate challenge.
+	Verifier   *jwt.Verifier     // Verifier of tokens.
+	NewClaims  func() jwt.Claims // NewClaims returns the claims that the token is decoded into.
+	CookieName string            // Name of cookie which contains the token, empty means disabled.
+	QueryParam string            // Name of query parameter which contains the token, empty means disabled.
+}
+
+// NewJWTConfig returns default JWT authentication configuration,
+// which reads the token from the Authorization header and decodes it into jwt.RegisteredClaims.
+func NewJWTConfig(verifier *jwt.Verifier) *JWTConfig {
+	return &JWTConfig{
+		Realm:    "Restricted",
+		Verifier: verifier,
+		NewClaims: func() jwt.Claims {
+			return &jwt.RegisteredClaims{}
+		},
+	}
+}
+
+// JWTMiddleware authenticates the request by JSON Web Token, the token is read from
+// the Authorization header, cookie and query string in order.
+//
+// The claims are the principal of the request, which is available by Context.JWTClaims,
+// the unauthenticated requests will be handled by Context.HandleError with status code 401,
+// and the WWW-Authenticate challenge.
+type JWTMiddleware struct {
+	config *JWTConfig
+}
+
+// NewJWTMiddleware returns a JWTMiddleware's instance.
+func NewJWTMiddleware(config *JWTConfig) *JWTMiddleware {
+	return &JWTMiddleware{config: config}
+}
+
+// Handle implemented Middleware Interface.
+func (m *JWTMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		token, ok := parseBearerToken(ctx.Request.Header.Peek("Authorization"))
+		if !ok && m.config.CookieName != "" {
+			token = string(ctx.Request.Header.Cookie(m.config.CookieName))
+		}
+		if token == "" && m.config.QueryParam != "" {
+			token = string(ctx.QueryArgs().Peek(m.config.QueryParam))
+		}
+		if token == "" {
+			m.unauthorized(ctx, ErrMissingCredentials)
+			return
+		}
+
+		claims := m.config.NewClaims()
+		if err := m.config.Verifier.Verify(token, claims); err != nil {
+			m.unauthorized(ctx, err)
+			return
+		}
+
+		ctx.SetPrincipal(claims)
+		next.Handle(ctx)
+	})
+}
+
+func (m *JWTMiddleware) unauthorized(ctx *Context, err error) {
+	ctx.HandleError(fasthttp.StatusUnauthorized, err)
+	challenge := "Bearer realm=" + strconv.Quote(m.config.Realm)
+	if err != ErrMissingCredentials {
+		challenge += `, error="invalid_token"`
+	}
+	ctx.Response.Header.Set("WWW-Authenticate", challenge)
+}
+
+// JWTClaims returns the claims of the current request, it is set by JWTMiddleware,
+// and can be asserted to the type returned by JWTConfig.NewClaims.
+//
+// Returns nil if the request is not authenticated by JWT.
+func (ctx *Context) JWTClaims() jwt.Claims {
+	ctx.checkReleased()
+	claims, _ := ctx.principal.(jwt.Claims)
+	return claims
+}
@@ -0,0 +1,63 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// TokenIssuer issues and refreshes the tokens.
+type TokenIssuer struct {
+	Signer   *Signer          // Signer of tokens.
+	Issuer   string           // Issuer of tokens, empty means no iss claim.
+	Audience Audience         // Audience of tokens, empty means no aud claim.
+	TTL      time.Duration    // Lifetime of tokens, zero means that the tokens never expire.
+	Now      func() time.Time // Current time, defaults to time.Now.
+}
+
+// Issue returns the signed token of the claims.
+//
+// The iat, exp and jti claims are set, and so are the iss and aud claims if they are empty.
+func (i *TokenIssuer) Issue(claims Claims) (string, error) {
+	now := time.Now()
+	if i.Now != nil {
+		now = i.Now()
+	}
+
+	c := claims.Registered()
+	c.IssuedAt = NewNumericDate(now)
+	c.ExpiresAt = nil
+	if i.TTL > 0 {
+		c.ExpiresAt = NewNumericDate(now.Add(i.TTL))
+	}
+	if c.Issuer == "" {
+		c.Issuer = i.Issuer
+	}
+	if len(c.Audience) == 0 {
+		c.Audience = i.Audience
+	}
+	if c.ID == "" {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return "", err
+		}
+		c.ID = hex.EncodeToString(id)
+	}
+
+	return i.Signer.Sign(claims)
+}
+
+// Refresh verifies the token by verifier, and issues a new token with the same claims,
+// the token that expired less than window ago can be refreshed as well.
+//
+// The claims are decoded from the token, the new token has new iat, exp and jti claims.
+func (i *TokenIssuer) Refresh(token string, claims Claims, verifier *Verifier, window time.Duration) (string, error) {
+	if err := verifier.verify(token, claims, window); err != nil {
+		return "", err
+	}
+
+	c := claims.Registered()
+	c.NotBefore = nil
+	c.ID = ""
+	return i.Issue(claims)
+}
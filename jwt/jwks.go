@@ -0,0 +1,329 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUnsupportedKey means that the JSON Web Key's type or curve is not supported.
+var ErrUnsupportedKey = errors.New("jwt: unsupported key")
+
+// JWK is a JSON Web Key (RFC 7517).
+type JWK struct {
+	KeyID     string      // Key ID.
+	Algorithm string      // Algorithm of the key, optional.
+	Use       string      // Intended use of the key, such as "sig", optional.
+	Key       interface{} // []byte, *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+}
+
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use,omitempty"`
+	K         string `json:"k,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// MarshalJSON encodes the key, the private keys are encoded as their public keys.
+func (k JWK) MarshalJSON() ([]byte, error) {
+	v := jsonWebKey{KeyID: k.KeyID, Algorithm: k.Algorithm, Use: k.Use}
+	switch key := publicKey(k.Key).(type) {
+	case []byte:
+		v.KeyType, v.K = "oct", encoding.EncodeToString(key)
+	case *rsa.PublicKey:
+		v.KeyType = "RSA"
+		v.N = encoding.EncodeToString(key.N.Bytes())
+		v.E = encoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return nil, ErrUnsupportedKey
+		}
+		v.KeyType, v.Curve = "EC", "P-256"
+		v.X = encoding.EncodeToString(key.X.FillBytes(make([]byte, 32)))
+		v.Y = encoding.EncodeToString(key.Y.FillBytes(make([]byte, 32)))
+	case ed25519.PublicKey:
+		v.KeyType, v.Curve, v.X = "OKP", "Ed25519", encoding.EncodeToString(key)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes the key.
+func (k *JWK) UnmarshalJSON(data []byte) error {
+	var v jsonWebKey
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	key, err := v.key()
+	if err != nil {
+		return err
+	}
+	*k = JWK{KeyID: v.KeyID, Algorithm: v.Algorithm, Use: v.Use, Key: key}
+	return nil
+}
+
+func (v jsonWebKey) key() (interface{}, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := encoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("jwt: invalid %s key", v.KeyType)
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch v.KeyType {
+	case "oct":
+		return encoding.DecodeString(v.K)
+	case "RSA":
+		n, err := decode(v.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(v.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, ErrUnsupportedKey
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if v.Curve != "P-256" {
+			return nil, ErrUnsupportedKey
+		}
+		x, err := decode(v.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(v.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("jwt: invalid EC key")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "OKP":
+		if v.Curve != "Ed25519" {
+			return nil, ErrUnsupportedKey
+		}
+		x, err := encoding.DecodeString(v.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("jwt: invalid OKP key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, ErrUnsupportedKey
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ParseJWKS parses the JSON Web Key Set, the unsupported keys are skipped.
+func ParseJWKS(data []byte) (*JWKS, error) {
+	var raw struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	set := &JWKS{}
+	for _, v := range raw.Keys {
+		var k JWK
+		if err := json.Unmarshal(v, &k); err != nil {
+			if err == ErrUnsupportedKey {
+				continue
+			}
+			return nil, err
+		}
+		set.Keys = append(set.Keys, k)
+	}
+	return set, nil
+}
+
+// Key implemented KeySet Interface.
+//
+// The key is matched by key ID if it is non-empty, otherwise the key must be the only one
+// that is suitable for the algorithm.
+func (s *JWKS) Key(kid, alg string) (interface{}, error) {
+	var found interface{}
+	for _, k := range s.Keys {
+		if (k.Use != "" && k.Use != "sig") || (k.Algorithm != "" && k.Algorithm != alg) || !suitable(alg, k.Key) {
+			continue
+		}
+		if kid != "" {
+			if k.KeyID == kid {
+				return k.Key, nil
+			}
+			continue
+		}
+		if found != nil {
+			// Ambiguous key.
+			return nil, ErrKeyNotFound
+		}
+		found = k.Key
+	}
+	if found == nil {
+		return nil, ErrKeyNotFound
+	}
+	return found, nil
+}
+
+// suitable reports whether the key's type matches the algorithm.
+func suitable(alg string, key interface{}) bool {
+	switch key.(type) {
+	case []byte:
+		return alg == HS256
+	case *rsa.PublicKey:
+		return alg == RS256
+	case *ecdsa.PublicKey:
+		return alg == ES256
+	case ed25519.PublicKey:
+		return alg == EdDSA
+	}
+	return false
+}
+
+// JWKSLoader loads the JSON Web Key Set's content.
+type JWKSLoader func() ([]byte, error)
+
+// JWKSFile returns a JWKSLoader that reads the file.
+func JWKSFile(path string) JWKSLoader {
+	return func() ([]byte, error) {
+		return ioutil.ReadFile(path)
+	}
+}
+
+// JWKSURL returns a JWKSLoader that fetches the URL.
+func JWKSURL(url string) JWKSLoader {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func() ([]byte, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("jwt: failed to fetch JWKS: %s", resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	}
+}
+
+// RotatingKeySet is a KeySet which reloads the JSON Web Key Set periodically,
+// and on the unknown key ID, so that the keys can be rotated without restarting.
+//
+// The previous keys are kept if the reloading fails.
+type RotatingKeySet struct {
+	loader JWKSLoader
+	// Interval of reloading.
+	Interval time.Duration
+	// Minimum interval of reloading on the unknown key ID, which prevents flooding the source.
+	MinInterval time.Duration
+
+	mu       sync.RWMutex
+	keys     *JWKS
+	loadedAt time.Time
+	now      func() time.Time
+}
+
+// NewRotatingKeySet returns a RotatingKeySet that reloads the keys every interval.
+func NewRotatingKeySet(loader JWKSLoader, interval time.Duration) *RotatingKeySet {
+	return &RotatingKeySet{
+		loader:      loader,
+		Interval:    interval,
+		MinInterval: time.Minute,
+		now:         time.Now,
+	}
+}
+
+// Refresh reloads the keys.
+func (s *RotatingKeySet) Refresh() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.refresh()
+}
+
+func (s *RotatingKeySet) refresh() error {
+	// Update the time whether or not it succeeds, so that the failures are not retried on every request.
+	s.loadedAt = s.now()
+
+	data, err := s.loader()
+	if err != nil {
+		return err
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	return nil
+}
+
+// Key implemented KeySet Interface.
+func (s *RotatingKeySet) Key(kid, alg string) (interface{}, error) {
+	s.mu.RLock()
+	keys, loadedAt := s.keys, s.loadedAt
+	s.mu.RUnlock()
+
+	if keys == nil || s.now().Sub(loadedAt) >= s.Interval {
+		if keys, err := s.reload(loadedAt); keys == nil {
+			return nil, err
+		}
+	}
+
+	key, err := s.current().Key(kid, alg)
+	if err == ErrKeyNotFound && s.now().Sub(s.loaded()) >= s.MinInterval {
+		// The key may be rotated.
+		if keys, _ := s.reload(s.loaded()); keys != nil {
+			return keys.Key(kid, alg)
+		}
+	}
+	return key, err
+}
+
+// reload reloads the keys if they have not been reloaded since loadedAt, and returns the current keys.
+func (s *RotatingKeySet) reload(loadedAt time.Time) (*JWKS, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	if s.loadedAt.Equal(loadedAt) {
+		err = s.refresh()
+	}
+	if s.keys == nil && err == nil {
+		err = ErrKeyNotFound
+	}
+	return s.keys, err
+}
+
+func (s *RotatingKeySet) current() *JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys
+}
+
+func (s *RotatingKeySet) loaded() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loadedAt
+}
@@ -0,0 +1,195 @@
+// Package jwt implements the JSON Web Token (RFC 7519) signing and verification,
+// with the HS256, RS256, ES256 and EdDSA algorithms and the JSON Web Key Set (RFC 7517).
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// Algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
+
+var (
+	// ErrMalformed means that the token is not a valid JWS compact serialization.
+	ErrMalformed = errors.New("jwt: malformed token")
+	// ErrAlgorithm means that the token's algorithm is not allowed or doesn't match the key.
+	ErrAlgorithm = errors.New("jwt: unexpected algorithm")
+	// ErrSignature means that the token's signature is invalid.
+	ErrSignature = errors.New("jwt: invalid signature")
+	// ErrKeyNotFound means that there is no key for the token.
+	ErrKeyNotFound = errors.New("jwt: key not found")
+	// ErrInvalidKey means that the key's type doesn't match the algorithm.
+	ErrInvalidKey = errors.New("jwt: invalid key")
+)
+
+// Header is the JOSE header.
+type Header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+var encoding = base64.RawURLEncoding
+
+// Signer signs the tokens.
+type Signer struct {
+	Algorithm string      // Algorithm of signature.
+	KeyID     string      // Key ID in header, optional.
+	Key       interface{} // []byte for HS256, *rsa.PrivateKey for RS256, *ecdsa.PrivateKey for ES256, ed25519.PrivateKey for EdDSA.
+}
+
+// Sign returns the signed token of the claims.
+func (s *Signer) Sign(claims interface{}) (string, error) {
+	header, err := json.Marshal(Header{Algorithm: s.Algorithm, Type: "JWT", KeyID: s.KeyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	input := encoding.EncodeToString(header) + "." + encoding.EncodeToString(payload)
+	signature, err := sign(s.Algorithm, s.Key, []byte(input))
+	if err != nil {
+		return "", err
+	}
+	return input + "." + encoding.EncodeToString(signature), nil
+}
+
+// split decodes the header, payload and signature of token.
+func split(token string) (header Header, payload, input, signature []byte, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		err = ErrMalformed
+		return
+	}
+
+	headerJSON, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		err = ErrMalformed
+		return
+	}
+	if err = json.Unmarshal(headerJSON, &header); err != nil {
+		err = ErrMalformed
+		return
+	}
+	if payload, err = encoding.DecodeString(parts[1]); err != nil {
+		err = ErrMalformed
+		return
+	}
+	if signature, err = encoding.DecodeString(parts[2]); err != nil {
+		err = ErrMalformed
+		return
+	}
+	input = []byte(parts[0] + "." + parts[1])
+	return
+}
+
+func sign(alg string, key interface{}, input []byte) ([]byte, error) {
+	switch alg {
+	case HS256:
+		k, ok := key.([]byte)
+		if !ok || len(k) == 0 {
+			return nil, ErrInvalidKey
+		}
+		h := hmac.New(sha256.New, k)
+		h.Write(input)
+		return h.Sum(nil), nil
+	case RS256:
+		k, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, ErrInvalidKey
+		}
+		digest := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case ES256:
+		k, ok := key.(*ecdsa.PrivateKey)
+		if !ok || k.Curve.Params().BitSize != 256 {
+			return nil, ErrInvalidKey
+		}
+		digest := sha256.Sum256(input)
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// The signature is R and S in 32 bytes big-endian each.
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	case EdDSA:
+		k, ok := key.(ed25519.PrivateKey)
+		if !ok || len(k) != ed25519.PrivateKeySize {
+			return nil, ErrInvalidKey
+		}
+		return ed25519.Sign(k, input), nil
+	}
+	return nil, ErrAlgorithm
+}
+
+func verify(alg string, key interface{}, input, signature []byte) error {
+	switch alg {
+	case HS256:
+		k, ok := key.([]byte)
+		if !ok || len(k) == 0 {
+			return ErrInvalidKey
+		}
+		h := hmac.New(sha256.New, k)
+		h.Write(input)
+		if !hmac.Equal(signature, h.Sum(nil)) {
+			return ErrSignature
+		}
+		return nil
+	case RS256:
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrInvalidKey
+		}
+		digest := sha256.Sum256(input)
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) != nil {
+			return ErrSignature
+		}
+		return nil
+	case ES256:
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok || k.Curve.Params().BitSize != 256 {
+			return ErrInvalidKey
+		}
+		if len(signature) != 64 {
+			return ErrSignature
+		}
+		digest := sha256.Sum256(input)
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(k, digest[:], r, s) {
+			return ErrSignature
+		}
+		return nil
+	case EdDSA:
+		k, ok := key.(ed25519.PublicKey)
+		if !ok || len(k) != ed25519.PublicKeySize {
+			return ErrInvalidKey
+		}
+		if !ed25519.Verify(k, input, signature) {
+			return ErrSignature
+		}
+		return nil
+	}
+	return ErrAlgorithm
+}
@@ -0,0 +1,325 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type userClaims struct {
+	RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+}
+
+func generateKeys(t *testing.T) map[string]interface{} {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]interface{}{
+		HS256: []byte("secret"),
+		RS256: rsaKey,
+		ES256: ecKey,
+		EdDSA: edKey,
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	for alg, key := range generateKeys(t) {
+		signer := &Signer{Algorithm: alg, Key: key}
+		token, err := signer.Sign(&userClaims{
+			RegisteredClaims: RegisteredClaims{Subject: "foo", ExpiresAt: NewNumericDate(now.Add(time.Hour))},
+			Roles:            []string{"admin"},
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to sign: %s", alg, err)
+		}
+
+		verifier := &Verifier{
+			Keys: StaticKey(alg, publicKey(key)),
+			Now:  func() time.Time { return now },
+		}
+		claims := &userClaims{}
+		if err = verifier.Verify(token, claims); err != nil {
+			t.Fatalf("%s: failed to verify: %s", alg, err)
+		}
+		if claims.Subject != "foo" || len(claims.Roles) != 1 || claims.Roles[0] != "admin" {
+			t.Errorf("%s: unexpected claims %+v", alg, claims)
+		}
+
+		// Tamper the payload.
+		parts := strings.Split(token, ".")
+		payload, _ := json.Marshal(map[string]string{"sub": "bar"})
+		tampered := parts[0] + "." + encoding.EncodeToString(payload) + "." + parts[2]
+		if err = verifier.Verify(tampered, &userClaims{}); err != ErrSignature {
+			t.Errorf("%s: expected error %v, got %v", alg, ErrSignature, err)
+		}
+
+		// Only the allowed algorithms are accepted.
+		verifier.Algorithms = []string{"none"}
+		if err = verifier.Verify(token, &userClaims{}); err != ErrAlgorithm {
+			t.Errorf("%s: expected error %v, got %v", alg, ErrAlgorithm, err)
+		}
+	}
+}
+
+func TestVerifier_AlgorithmConfusion(t *testing.T) {
+	keys := generateKeys(t)
+	rsaKey := keys[RS256].(*rsa.PrivateKey)
+	verifier := &Verifier{Keys: StaticKey(RS256, &rsaKey.PublicKey)}
+
+	// The token signed with HS256 by public key must not be accepted.
+	signer := &Signer{Algorithm: HS256, Key: rsaKey.PublicKey.N.Bytes()}
+	token, err := signer.Sign(&RegisteredClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = verifier.Verify(token, &RegisteredClaims{}); err != ErrKeyNotFound {
+		t.Errorf("expected error %v, got %v", ErrKeyNotFound, err)
+	}
+
+	for _, token := range []string{"", "a.b", "!.b.c", "eyJhbGciOiJub25lIn0.e30."} {
+		if err = verifier.Verify(token, &RegisteredClaims{}); err == nil {
+			t.Errorf("%q: expected an error", token)
+		}
+	}
+}
+
+func TestVerifier_Claims(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	signer := &Signer{Algorithm: HS256, Key: []byte("secret")}
+	tests := []struct {
+		claims RegisteredClaims
+		leeway time.Duration
+		err    error
+	}{
+		{RegisteredClaims{Issuer: "iss", Audience: Audience{"aud"}}, 0, nil},
+		{RegisteredClaims{Issuer: "iss", Audience: Audience{"other", "aud"}}, 0, nil},
+		{RegisteredClaims{Issuer: "other", Audience: Audience{"aud"}}, 0, ErrIssuer},
+		{RegisteredClaims{Issuer: "iss", Audience: Audience{"other"}}, 0, ErrAudience},
+		{RegisteredClaims{Issuer: "iss", Audience: Audience{"aud"}, ExpiresAt: NewNumericDate(now)}, 0, ErrExpired},
+		{RegisteredClaims{Issuer: "iss", Audience: Audience{"aud"}, ExpiresAt: NewNumericDate(now)}, time.Minute, nil},
+		{RegisteredClaims{Issuer: "iss", Audience: Audience{"aud"}, NotBefore: NewNumericDate(now.Add(time.Second))}, 0, ErrNotValidYet},
+		{RegisteredClaims{Issuer: "iss", Audience: Audience{"aud"}, NotBefore: NewNumericDate(now.Add(time.Second))}, time.Minute, nil},
+	}
+	for i, test := range tests {
+		token, err := signer.Sign(&test.claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		verifier := &Verifier{
+			Keys:     StaticKey(HS256, []byte("secret")),
+			Issuer:   "iss",
+			Audience: "aud",
+			Leeway:   test.leeway,
+			Now:      func() time.Time { return now },
+		}
+		if err = verifier.Verify(token, &RegisteredClaims{}); err != test.err {
+			t.Errorf("%d: expected error %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestAudience(t *testing.T) {
+	var claims RegisteredClaims
+	if err := json.Unmarshal([]byte(`{"aud":"foo","exp":1600000000.5}`), &claims); err != nil {
+		t.Fatal(err)
+	}
+	if len(claims.Audience) != 1 || claims.Audience[0] != "foo" {
+		t.Errorf("unexpected audience %v", claims.Audience)
+	}
+	if claims.ExpiresAt.UnixNano() != 1600000000500000000 {
+		t.Errorf("unexpected expiration %v", claims.ExpiresAt)
+	}
+
+	data, _ := json.Marshal(RegisteredClaims{Audience: Audience{"foo"}})
+	if string(data) != `{"aud":"foo"}` {
+		t.Errorf("unexpected JSON %s", data)
+	}
+	data, _ = json.Marshal(RegisteredClaims{Audience: Audience{"foo", "bar"}})
+	if string(data) != `{"aud":["foo","bar"]}` {
+		t.Errorf("unexpected JSON %s", data)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	keys := generateKeys(t)
+	set := &JWKS{}
+	for alg, key := range keys {
+		set.Keys = append(set.Keys, JWK{KeyID: "key-" + alg, Algorithm: alg, Use: "sig", Key: key})
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"d"`) {
+		t.Errorf("private key is exposed: %s", data)
+	}
+
+	parsed, err := ParseJWKS(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for alg, key := range keys {
+		token, err := (&Signer{Algorithm: alg, KeyID: "key-" + alg, Key: key}).Sign(&RegisteredClaims{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = (&Verifier{Keys: parsed}).Verify(token, &RegisteredClaims{}); err != nil {
+			t.Errorf("%s: failed to verify: %s", alg, err)
+		}
+
+		token, _ = (&Signer{Algorithm: alg, KeyID: "unknown", Key: key}).Sign(&RegisteredClaims{})
+		if err = (&Verifier{Keys: parsed}).Verify(token, &RegisteredClaims{}); err != ErrKeyNotFound {
+			t.Errorf("%s: expected error %v, got %v", alg, ErrKeyNotFound, err)
+		}
+	}
+
+	// The unsupported keys are skipped.
+	parsed, err = ParseJWKS([]byte(`{"keys":[{"kty":"EC","crv":"P-521"},{"kty":"oct","k":"c2VjcmV0"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Keys) != 1 {
+		t.Errorf("unexpected keys %v", parsed.Keys)
+	}
+}
+
+func TestRotatingKeySet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "jwks.json")
+	write := func(keys ...JWK) {
+		data, _ := json.Marshal(JWKS{Keys: keys})
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Unix(1600000000, 0)
+	set := NewRotatingKeySet(JWKSFile(path), time.Hour)
+	set.now = func() time.Time { return now }
+
+	if _, err = set.Key("", HS256); err == nil {
+		t.Error("expected an error if the file doesn't exist")
+	}
+
+	now = now.Add(time.Minute)
+	write(JWK{KeyID: "1", Key: []byte("one")})
+	if key, err := set.Key("1", HS256); err != nil || string(key.([]byte)) != "one" {
+		t.Errorf("unexpected key %v, %v", key, err)
+	}
+
+	// The unknown key ID triggers reloading, but no more than once per MinInterval.
+	write(JWK{KeyID: "1", Key: []byte("one")}, JWK{KeyID: "2", Key: []byte("two")})
+	if _, err := set.Key("2", HS256); err != ErrKeyNotFound {
+		t.Errorf("expected error %v, got %v", ErrKeyNotFound, err)
+	}
+	now = now.Add(time.Minute)
+	if key, err := set.Key("2", HS256); err != nil || string(key.([]byte)) != "two" {
+		t.Errorf("unexpected key %v, %v", key, err)
+	}
+
+	// The keys are reloaded periodically, the previous keys are kept if it fails.
+	write(JWK{KeyID: "3", Key: []byte("three")})
+	now = now.Add(time.Hour)
+	if _, err := set.Key("1", HS256); err != ErrKeyNotFound {
+		t.Errorf("expected error %v, got %v", ErrKeyNotFound, err)
+	}
+	os.Remove(path)
+	now = now.Add(time.Hour)
+	if key, err := set.Key("3", HS256); err != nil || string(key.([]byte)) != "three" {
+		t.Errorf("unexpected key %v, %v", key, err)
+	}
+}
+
+func TestJWKSURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/jwks.json" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(JWKS{Keys: []JWK{{KeyID: "1", Key: []byte("one")}}})
+	}))
+	defer server.Close()
+
+	set := NewRotatingKeySet(JWKSURL(server.URL+"/jwks.json"), time.Hour)
+	if key, err := set.Key("1", HS256); err != nil || string(key.([]byte)) != "one" {
+		t.Errorf("unexpected key %v, %v", key, err)
+	}
+
+	if _, err := JWKSURL(server.URL + "/missing")(); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestTokenIssuer(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	clock := func() time.Time { return now }
+	issuer := &TokenIssuer{
+		Signer:   &Signer{Algorithm: HS256, Key: []byte("secret")},
+		Issuer:   "iss",
+		Audience: Audience{"aud"},
+		TTL:      time.Hour,
+		Now:      clock,
+	}
+	verifier := &Verifier{Keys: StaticKey(HS256, []byte("secret")), Issuer: "iss", Audience: "aud", Now: clock}
+
+	token, err := issuer.Issue(&userClaims{RegisteredClaims: RegisteredClaims{Subject: "foo"}, Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := &userClaims{}
+	if err = verifier.Verify(token, claims); err != nil {
+		t.Fatal(err)
+	}
+	if !claims.ExpiresAt.Equal(now.Add(time.Hour)) || !claims.IssuedAt.Equal(now) || claims.ID == "" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	id := claims.ID
+
+	// The expired token can be refreshed within the window.
+	now = now.Add(90 * time.Minute)
+	if _, err = issuer.Refresh(token, &userClaims{}, verifier, time.Minute); err != ErrExpired {
+		t.Errorf("expected error %v, got %v", ErrExpired, err)
+	}
+	refreshed, err := issuer.Refresh(token, &userClaims{}, verifier, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims = &userClaims{}
+	if err = verifier.Verify(refreshed, claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "foo" || len(claims.Roles) != 1 || !claims.ExpiresAt.Equal(now.Add(time.Hour)) || claims.ID == id {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	if _, err = issuer.Refresh("invalid", &userClaims{}, verifier, time.Hour); !errors.Is(err, ErrMalformed) {
+		t.Errorf("expected error %v, got %v", ErrMalformed, err)
+	}
+}
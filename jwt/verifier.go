@@ -0,0 +1,133 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrExpired means that the token is expired.
+	ErrExpired = errors.New("jwt: token is expired")
+	// ErrNotValidYet means that the token is not valid yet.
+	ErrNotValidYet = errors.New("jwt: token is not valid yet")
+	// ErrIssuer means that the token's issuer is unexpected.
+	ErrIssuer = errors.New("jwt: unexpected issuer")
+	// ErrAudience means that the token is not intended for the audience.
+	ErrAudience = errors.New("jwt: unexpected audience")
+)
+
+// KeySet provides the keys for verification.
+type KeySet interface {
+	// Key returns the key of the key ID and algorithm, the key ID may be empty.
+	Key(kid, alg string) (interface{}, error)
+}
+
+// StaticKey returns a KeySet of a single key, the key is the secret for HS256,
+// or the public or private key for the others.
+func StaticKey(alg string, key interface{}) KeySet {
+	return staticKey{alg: alg, key: key}
+}
+
+type staticKey struct {
+	alg string
+	key interface{}
+}
+
+// Key implemented KeySet Interface.
+func (k staticKey) Key(kid, alg string) (interface{}, error) {
+	if alg != k.alg {
+		return nil, ErrKeyNotFound
+	}
+	return k.key, nil
+}
+
+// Verifier verifies the tokens and validates the registered claims.
+type Verifier struct {
+	Keys       KeySet           // Keys for verification.
+	Algorithms []string         // Allowed algorithms, empty means all the supported algorithms.
+	Issuer     string           // Expected issuer, empty means any issuer.
+	Audience   string           // Expected audience, empty means any audience.
+	Leeway     time.Duration    // Leeway of exp and nbf for clock skew.
+	Now        func() time.Time // Current time, defaults to time.Now.
+}
+
+// Verify verifies the token's signature, decodes the payload into claims and validates them.
+func (v *Verifier) Verify(token string, claims Claims) error {
+	return v.verify(token, claims, 0)
+}
+
+// verify verifies the token, the token expired less than extra ago is treated as valid.
+func (v *Verifier) verify(token string, claims Claims, extra time.Duration) error {
+	header, payload, input, signature, err := split(token)
+	if err != nil {
+		return err
+	}
+
+	if !v.isAllowed(header.Algorithm) {
+		return ErrAlgorithm
+	}
+	key, err := v.Keys.Key(header.KeyID, header.Algorithm)
+	if err != nil {
+		return err
+	}
+	if err = verify(header.Algorithm, publicKey(key), input, signature); err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(payload, claims); err != nil {
+		return ErrMalformed
+	}
+	return v.validate(claims.Registered(), extra)
+}
+
+// validate validates the registered claims.
+func (v *Verifier) validate(c *RegisteredClaims, extra time.Duration) error {
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+
+	if c.ExpiresAt != nil && !now.Before(c.ExpiresAt.Add(v.Leeway+extra)) {
+		return ErrExpired
+	}
+	if c.NotBefore != nil && now.Add(v.Leeway).Before(c.NotBefore.Time) {
+		return ErrNotValidYet
+	}
+	if v.Issuer != "" && c.Issuer != v.Issuer {
+		return ErrIssuer
+	}
+	if v.Audience != "" && !c.Audience.Contains(v.Audience) {
+		return ErrAudience
+	}
+	return nil
+}
+
+// isAllowed reports whether the algorithm is allowed.
+func (v *Verifier) isAllowed(alg string) bool {
+	if len(v.Algorithms) == 0 {
+		return alg == HS256 || alg == RS256 || alg == ES256 || alg == EdDSA
+	}
+	for _, a := range v.Algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// publicKey returns the public key of the private key, or the key itself.
+func publicKey(key interface{}) interface{} {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey
+	case ed25519.PrivateKey:
+		return k.Public()
+	}
+	return key
+}
@@ -0,0 +1,67 @@
+package clevergo
+
+import (
+	"github.com/headwindfly/clevergo/jwt"
+	"testing"
+	"time"
+)
+
+type testJWTClaims struct {
+	jwt.RegisteredClaims
+	Name string `json:"name"`
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	issuer := &jwt.TokenIssuer{
+		Signer: &jwt.Signer{Algorithm: jwt.HS256, Key: []byte("secret")},
+		TTL:    time.Hour,
+	}
+	token, err := issuer.Issue(&testJWTClaims{Name: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.Now = func() time.Time {
+		return time.Now().Add(-2 * time.Hour)
+	}
+	expired, err := issuer.Issue(&testJWTClaims{Name: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := NewJWTConfig(&jwt.Verifier{Keys: jwt.StaticKey(jwt.HS256, []byte("secret"))})
+	config.NewClaims = func() jwt.Claims {
+		return &testJWTClaims{}
+	}
+	config.CookieName = "token"
+	config.QueryParam = "token"
+	r := NewRouter()
+	r.AddMiddleware(NewJWTMiddleware(config))
+	r.GET("/", HandlerFunc(func(ctx *Context) {
+		ctx.Text(ctx.JWTClaims().(*testJWTClaims).Name)
+	}))
+
+	tests := []struct {
+		request   string
+		code      int
+		challenge string
+	}{
+		{"GET / HTTP/1.1\r\nAuthorization: Bearer " + token + "\r\n\r\n", 200, ""},
+		{"GET / HTTP/1.1\r\nCookie: token=" + token + "\r\n\r\n", 200, ""},
+		{"GET /?token=" + token + " HTTP/1.1\r\n\r\n", 200, ""},
+		{"GET / HTTP/1.1\r\nAuthorization: Bearer " + expired + "\r\n\r\n", 401, `Bearer realm="Restricted", error="invalid_token"`},
+		{"GET / HTTP/1.1\r\nAuthorization: Bearer invalid\r\n\r\n", 401, `Bearer realm="Restricted", error="invalid_token"`},
+		{"GET / HTTP/1.1\r\n\r\n", 401, `Bearer realm="Restricted"`},
+	}
+	for i, test := range tests {
+		resp := serve(t, r.Handler, test.request)
+		if resp.StatusCode() != test.code {
+			t.Errorf("%d: unexpected status code %d. Expected %d", i, resp.StatusCode(), test.code)
+		}
+		if test.code == 200 && string(resp.Body()) != "foo" {
+			t.Errorf("%d: unexpected body %q", i, resp.Body())
+		}
+		if challenge := string(resp.Header.Peek("WWW-Authenticate")); challenge != test.challenge {
+			t.Errorf("%d: unexpected challenge %q. Expected %q", i, challenge, test.challenge)
+		}
+	}
+}
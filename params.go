@@ -0,0 +1,252 @@
+package clevergo
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/clevergo/router"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound means that the requested resource is not found.
+	ErrNotFound = errors.New("not found")
+	// ErrParamMissing means that the param doesn't exist or it is empty.
+	ErrParamMissing = errors.New("missing param")
+)
+
+// ParamError is the error of converting a param.
+type ParamError struct {
+	Name  string // Name of param.
+	Value string // Value of param.
+	Err   error  // The underlying error.
+}
+
+// Error implemented error Interface.
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("invalid param %s %q: %s", e.Name, e.Value, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+// Param returns the value of param, or an empty string if it doesn't exist.
+func (ctx *Context) Param(name string) string {
+	ctx.checkReleased()
+	if ctx.Params == nil {
+		return ""
+	}
+	return ctx.Params.ByName(name)
+}
+
+// param returns the non-empty value of param.
+func (ctx *Context) param(name string) (string, error) {
+	value := ctx.Param(name)
+	if value == "" {
+		return "", &ParamError{Name: name, Err: ErrParamMissing}
+	}
+	return value, nil
+}
+
+// ParamInt returns the value of param as int.
+func (ctx *Context) ParamInt(name string) (int, error) {
+	ctx.checkReleased()
+	value, err := ctx.param(name)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, &ParamError{Name: name, Value: value, Err: err}
+	}
+	return i, nil
+}
+
+// ParamUUID returns the value of param as UUID.
+func (ctx *Context) ParamUUID(name string) (UUID, error) {
+	ctx.checkReleased()
+	value, err := ctx.param(name)
+	if err != nil {
+		return UUID{}, err
+	}
+	uuid, err := ParseUUID(value)
+	if err != nil {
+		return UUID{}, &ParamError{Name: name, Value: value, Err: err}
+	}
+	return uuid, nil
+}
+
+// ParamTime returns the value of param as time in the layout, such as "2006-01-02".
+func (ctx *Context) ParamTime(name, layout string) (time.Time, error) {
+	ctx.checkReleased()
+	value, err := ctx.param(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, &ParamError{Name: name, Value: value, Err: err}
+	}
+	return t, nil
+}
+
+// ErrInvalidUUID means that the string is not a valid UUID.
+var ErrInvalidUUID = errors.New("invalid UUID")
+
+// UUID is a universally unique identifier (RFC 4122).
+type UUID [16]byte
+
+// ParseUUID parses the UUID in the canonical form, such as "f47ac10b-58cc-0372-8567-0e02b2c3d479".
+func ParseUUID(s string) (UUID, error) {
+	var uuid UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return uuid, ErrInvalidUUID
+	}
+	if _, err := hex.Decode(uuid[:], []byte(s[0:8]+s[9:13]+s[14:18]+s[19:23]+s[24:])); err != nil {
+		return uuid, ErrInvalidUUID
+	}
+	return uuid, nil
+}
+
+// String returns the canonical form of UUID.
+func (u UUID) String() string {
+	s := hex.EncodeToString(u[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// routeConstraintsMu protects routeConstraints.
+var routeConstraintsMu sync.RWMutex
+
+// routeConstraints are the named constraints of route params.
+var routeConstraints = map[string]func(value string) bool{
+	"int": func(value string) bool {
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	},
+	"uint": func(value string) bool {
+		_, err := strconv.ParseUint(value, 10, 64)
+		return err == nil
+	},
+	"uuid": func(value string) bool {
+		_, err := ParseUUID(value)
+		return err == nil
+	},
+	"alpha": regexp.MustCompile(`^[A-Za-z]+$`).MatchString,
+	"alnum": regexp.MustCompile(`^[A-Za-z0-9]+$`).MatchString,
+}
+
+// RegisterRouteConstraint registers a named constraint of route params, such as "slug" for "/posts/:name<slug>".
+//
+// The built-in constraints are "int", "uint", "uuid", "alpha" and "alnum",
+// the unknown constraints are treated as regular expressions, such as "/posts/:year<\d{4}>".
+func RegisterRouteConstraint(name string, match func(value string) bool) {
+	routeConstraintsMu.Lock()
+	defer routeConstraintsMu.Unlock()
+	routeConstraints[name] = match
+}
+
+// routePattern is the compiled route's pattern.
+type routePattern struct {
+	path        string                     // path without constraints.
+	constraints map[string]routeConstraint // constraints keyed by param name.
+}
+
+// routeConstraint is the constraint of a route param.
+type routeConstraint struct {
+	match    func(value string) bool
+	catchAll bool // whether the param is catch-all, its leading slash is not matched.
+}
+
+// matchParam reports whether the value satisfies the constraint of param.
+func (rp *routePattern) matchParam(name, value string) bool {
+	c, ok := rp.constraints[name]
+	if !ok {
+		return true
+	}
+	if c.catchAll {
+		value = strings.TrimPrefix(value, "/")
+	}
+	return c.match(value)
+}
+
+// routePatterns caches the compiled patterns.
+var routePatterns sync.Map
+
+// compileRoute returns the compiled pattern of route, such as "/users/:id<int>".
+//
+// It panics if the regular expression of constraint is invalid.
+func compileRoute(pattern string) *routePattern {
+	if rp, ok := routePatterns.Load(pattern); ok {
+		return rp.(*routePattern)
+	}
+
+	rp := &routePattern{}
+	path := pattern
+	for {
+		i := strings.IndexAny(path, ":*")
+		if i < 0 {
+			rp.path += path
+			break
+		}
+		end := strings.IndexByte(path[i:], '/')
+		if end < 0 {
+			end = len(path)
+		} else {
+			end += i
+		}
+		name := path[i+1 : end]
+		start := strings.IndexByte(name, '<')
+		if start < 0 {
+			rp.path += path[:end]
+			path = path[end:]
+			continue
+		}
+
+		// The constraint ends with '>' that is followed by '/' or the end of pattern,
+		// so that the regular expression can contain '/'.
+		end = strings.Index(path[i:], ">/")
+		if end < 0 {
+			end = len(path) - 1
+		} else {
+			end += i
+		}
+		if path[end] != '>' {
+			panic(fmt.Sprintf("invalid constraint of route %q", pattern))
+		}
+		name = path[i+1 : i+1+start]
+		constraint := path[i+1+start+1 : end]
+		catchAll := path[i] == '*'
+		rp.path += path[:i+1] + name
+		path = path[end+1:]
+
+		if rp.constraints == nil {
+			rp.constraints = make(map[string]routeConstraint)
+		}
+		routeConstraintsMu.RLock()
+		match, ok := routeConstraints[constraint]
+		routeConstraintsMu.RUnlock()
+		if !ok {
+			match = regexp.MustCompile(`^(?:` + constraint + `)$`).MatchString
+		}
+		rp.constraints[name] = routeConstraint{match: match, catchAll: catchAll}
+	}
+
+	routePatterns.Store(pattern, rp)
+	return rp
+}
+
+// matchParams reports whether the params satisfy the constraints.
+func (rp *routePattern) matchParams(ps router.Params) bool {
+	for name := range rp.constraints {
+		if !rp.matchParam(name, ps.ByName(name)) {
+			return false
+		}
+	}
+	return true
+}
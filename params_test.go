@@ -0,0 +1,144 @@
+package clevergo
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestContext_ParamAccessors(t *testing.T) {
+	var (
+		id      int
+		idErr   error
+		uuid    UUID
+		uuidErr error
+		date    time.Time
+		dateErr error
+	)
+	r := NewRouter()
+	r.GET("/posts/:id/:uuid/:date", HandlerFunc(func(ctx *Context) {
+		id, idErr = ctx.ParamInt("id")
+		uuid, uuidErr = ctx.ParamUUID("uuid")
+		date, dateErr = ctx.ParamTime("date", "2006-01-02")
+	}))
+
+	serve(t, r.Handler, "GET /posts/42/F47AC10B-58CC-4372-A567-0E02B2C3D479/2020-02-29 HTTP/1.1\r\n\r\n")
+	if idErr != nil || id != 42 {
+		t.Errorf("unexpected id %d, %v", id, idErr)
+	}
+	if uuidErr != nil || uuid.String() != "f47ac10b-58cc-4372-a567-0e02b2c3d479" {
+		t.Errorf("unexpected uuid %s, %v", uuid, uuidErr)
+	}
+	if dateErr != nil || !date.Equal(time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date %s, %v", date, dateErr)
+	}
+
+	serve(t, r.Handler, "GET /posts/foo/f47ac10b58cc4372a5670e02b2c3d479/2020-02-30 HTTP/1.1\r\n\r\n")
+	var paramErr *ParamError
+	if !errors.As(idErr, &paramErr) || paramErr.Name != "id" || paramErr.Value != "foo" || !errors.Is(idErr, strconv.ErrSyntax) {
+		t.Errorf("unexpected error %v", idErr)
+	}
+	if !errors.Is(uuidErr, ErrInvalidUUID) {
+		t.Errorf("unexpected error %v", uuidErr)
+	}
+	if dateErr == nil {
+		t.Error("expected an error")
+	}
+
+	ctx := NewContext(r, nil, nil)
+	if _, err := ctx.ParamInt("id"); !errors.Is(err, ErrParamMissing) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestRouter_Constraints(t *testing.T) {
+	RegisterRouteConstraint("slug", func(value string) bool {
+		for _, c := range value {
+			if !(c >= 'a' && c <= 'z' || c == '-') {
+				return false
+			}
+		}
+		return value != ""
+	})
+
+	var handled error
+	r := NewRouter()
+	r.SetErrorHandler(func(ctx *Context, code int, err error) {
+		handled = err
+		ctx.Error(err.Error(), code)
+	})
+	r.AddMiddleware(simpleMiddleware{})
+	handler := HandlerFunc(func(ctx *Context) {
+		ctx.Text(ctx.Route())
+	})
+	r.GET("/users/:id<int>", handler)
+	r.GET("/users/:id<int>/posts/:name<slug>", handler)
+	r.GET("/archives/:year<\\d{4}>/:month<0[1-9]|1[0-2]>", handler)
+	r.GET("/files/*path<[a-z/]+\\.txt>", handler)
+	r.GET("/items/:uuid<uuid>", handler)
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/users/42", 200},
+		{"/users/-1", 200},
+		{"/users/foo", 404},
+		{"/users/42/posts/hello-world", 200},
+		{"/users/42/posts/Hello", 404},
+		{"/archives/2020/02", 200},
+		{"/archives/2020/13", 404},
+		{"/archives/20/01", 404},
+		{"/files/a/b.txt", 200},
+		{"/files/a/b.png", 404},
+		{"/items/f47ac10b-58cc-4372-a567-0e02b2c3d479", 200},
+		{"/items/42", 404},
+	}
+	for _, test := range tests {
+		handled = nil
+		resp := serve(t, r.Handler, "GET "+test.path+" HTTP/1.1\r\n\r\n")
+		if resp.StatusCode() != test.code {
+			t.Errorf("%s: unexpected status code %d. Expected %d", test.path, resp.StatusCode(), test.code)
+		}
+		if test.code == 404 && handled != ErrNotFound {
+			t.Errorf("%s: unexpected error %v", test.path, handled)
+		}
+		// The request is rejected before the middlewares.
+		if test.code == 404 && len(resp.Header.Peek("Middleware")) > 0 {
+			t.Errorf("%s: the middlewares should not be invoked", test.path)
+		}
+	}
+
+	if methods := r.AllowedMethods("/users/42"); len(methods) != 3 {
+		t.Errorf("unexpected methods %v", methods)
+	}
+	if methods := r.AllowedMethods("/users/foo"); len(methods) != 0 {
+		t.Errorf("unexpected methods %v", methods)
+	}
+	if resp := serve(t, r.Handler, "GET /users/42 HTTP/1.1\r\n\r\n"); string(resp.Body()) != "/users/:id<int>" {
+		t.Errorf("unexpected route %q", resp.Body())
+	}
+}
+
+func TestCompileRoute(t *testing.T) {
+	tests := map[string]string{
+		"/users/:id":                       "/users/:id",
+		"/users/:id<int>/posts":            "/users/:id/posts",
+		"/a/:b<x/y>/c":                     "/a/:b/c",
+		"/files/*path<.*>":                 "/files/*path",
+		"/:lang<en|zh>/docs/:name<[a-z]+>": "/:lang/docs/:name",
+	}
+	for pattern, expected := range tests {
+		if path := compileRoute(pattern).path; path != expected {
+			t.Errorf("%s: unexpected path %q. Expected %q", pattern, path, expected)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic on invalid regular expression")
+		}
+	}()
+	compileRoute("/users/:id<[>")
+}
@@ -0,0 +1,212 @@
+package clevergo
+
+import (
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"net"
+	"strings"
+)
+
+// TrustedProxies is a list of trusted proxies' networks, the forwarding headers,
+// such as X-Forwarded-For and Forwarded, are only respected if the request comes from them.
+type TrustedProxies struct {
+	networks []*net.IPNet
+}
+
+// NewTrustedProxies returns a TrustedProxies of the CIDRs or IP addresses,
+// such as "10.0.0.0/8" and "127.0.0.1".
+func NewTrustedProxies(cidrs ...string) (*TrustedProxies, error) {
+	p := &TrustedProxies{}
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			p.networks = append(p.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %s", cidr, err)
+		}
+		p.networks = append(p.networks, network)
+	}
+	return p, nil
+}
+
+// Contains reports whether the IP is a trusted proxy.
+func (p *TrustedProxies) Contains(ip net.IP) bool {
+	if p == nil || ip == nil {
+		return false
+	}
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwarded is the client information of the request.
+type forwarded struct {
+	ip     net.IP
+	scheme string
+	host   string
+}
+
+// resolve returns the client information of the request, the forwarding headers are respected
+// only if the request comes from the trusted proxies.
+//
+// The hops are walked from right to left, the first one that is not a trusted proxy is the client.
+// The RFC 7239 Forwarded header takes precedence over the X-Forwarded-* headers.
+func (p *TrustedProxies) resolve(ctx *fasthttp.RequestCtx) forwarded {
+	f := forwarded{ip: ctx.RemoteIP(), scheme: "http", host: string(ctx.Host())}
+	if ctx.IsTLS() {
+		f.scheme = "https"
+	}
+	if !p.Contains(f.ip) {
+		return f
+	}
+
+	if header := joinHeader(&ctx.Request.Header, "Forwarded"); header != "" {
+		elements := parseForwarded(header)
+		for i := len(elements) - 1; i >= 0; i-- {
+			element := elements[i]
+			ip := parseForwardedFor(element["for"])
+			if ip != nil {
+				f.ip = ip
+			}
+			if v := element["proto"]; v != "" {
+				f.scheme = strings.ToLower(v)
+			}
+			if v := element["host"]; v != "" {
+				f.host = v
+			}
+			if !p.Contains(ip) {
+				break
+			}
+		}
+		return f
+	}
+
+	if header := joinHeader(&ctx.Request.Header, "X-Forwarded-For"); header != "" {
+		hops := strings.Split(header, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip != nil {
+				f.ip = ip
+			}
+			if !p.Contains(ip) {
+				break
+			}
+		}
+	}
+	// The last value is set by the nearest trusted proxy.
+	if v := lastHeaderValue(joinHeader(&ctx.Request.Header, "X-Forwarded-Proto")); v != "" {
+		f.scheme = strings.ToLower(v)
+	}
+	if v := lastHeaderValue(joinHeader(&ctx.Request.Header, "X-Forwarded-Host")); v != "" {
+		f.host = v
+	}
+	return f
+}
+
+// parseForwarded parses the elements of RFC 7239 Forwarded header,
+// such as `for=192.0.2.60;proto=http, for="[2001:db8::1]:4711"`.
+func parseForwarded(header string) []map[string]string {
+	var elements []map[string]string
+	for _, e := range splitQuoted(header, ',') {
+		element := make(map[string]string)
+		for _, pair := range splitQuoted(e, ';') {
+			i := strings.IndexByte(pair, '=')
+			if i < 0 {
+				continue
+			}
+			name := strings.ToLower(strings.TrimSpace(pair[:i]))
+			value := strings.TrimSpace(pair[i+1:])
+			if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+				value = strings.Replace(value[1:len(value)-1], `\"`, `"`, -1)
+			}
+			element[name] = value
+		}
+		elements = append(elements, element)
+	}
+	return elements
+}
+
+// splitQuoted splits the string by separator outside the quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseForwardedFor parses the node of "for" parameter, such as "192.0.2.60:8080" and "[2001:db8::1]:4711".
+//
+// Returns nil if it is unknown or obfuscated.
+func parseForwardedFor(node string) net.IP {
+	if strings.HasPrefix(node, "[") {
+		if i := strings.IndexByte(node, ']'); i > 0 {
+			return net.ParseIP(node[1:i])
+		}
+		return nil
+	}
+	if i := strings.IndexByte(node, ':'); i >= 0 && strings.Count(node, ":") == 1 {
+		node = node[:i]
+	}
+	return net.ParseIP(node)
+}
+
+// joinHeader returns the values of all header lines of the name joined by commas in order,
+// since the proxies may append a separate line instead of concatenating the values.
+func joinHeader(h *fasthttp.RequestHeader, name string) string {
+	var values []string
+	for _, value := range h.PeekAll(name) {
+		values = append(values, string(value))
+	}
+	return strings.Join(values, ",")
+}
+
+// lastHeaderValue returns the last value of the comma-separated header.
+func lastHeaderValue(header string) string {
+	values := strings.Split(header, ",")
+	return strings.TrimSpace(values[len(values)-1])
+}
+
+// RealIP returns the client IP, which is resolved from the forwarding headers
+// if the request comes from the router's trusted proxies.
+func (ctx *Context) RealIP() net.IP {
+	ctx.checkReleased()
+	return ctx.router.trustedProxies.resolve(ctx.RequestCtx).ip
+}
+
+// Scheme returns the scheme that the client used, "http" or "https", which is resolved
+// from the forwarding headers if the request comes from the router's trusted proxies.
+func (ctx *Context) Scheme() string {
+	ctx.checkReleased()
+	return ctx.router.trustedProxies.resolve(ctx.RequestCtx).scheme
+}
+
+// RealHost returns the host that the client requested, which is resolved
+// from the forwarding headers if the request comes from the router's trusted proxies.
+func (ctx *Context) RealHost() string {
+	ctx.checkReleased()
+	return ctx.router.trustedProxies.resolve(ctx.RequestCtx).host
+}
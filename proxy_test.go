@@ -0,0 +1,134 @@
+package clevergo
+
+import (
+	"github.com/valyala/fasthttp"
+	"net"
+	"testing"
+)
+
+func newProxyRequest(remoteIP string, headers map[string]string) *fasthttp.RequestCtx {
+	req := &fasthttp.Request{}
+	req.Header.SetHost("example.com")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(req, &net.TCPAddr{IP: net.ParseIP(remoteIP), Port: 1234}, nil)
+	return ctx
+}
+
+func TestNewTrustedProxies(t *testing.T) {
+	p, err := NewTrustedProxies("10.0.0.0/8", "127.0.0.1", "::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, expected := range map[string]bool{
+		"10.1.2.3":  true,
+		"127.0.0.1": true,
+		"127.0.0.2": false,
+		"::1":       true,
+		"1.2.3.4":   false,
+	} {
+		if p.Contains(net.ParseIP(ip)) != expected {
+			t.Errorf("%s: expected %t", ip, expected)
+		}
+	}
+
+	for _, cidr := range []string{"foo", "10.0.0.0/33"} {
+		if _, err = NewTrustedProxies(cidr); err == nil {
+			t.Errorf("%s: expected an error", cidr)
+		}
+	}
+}
+
+func TestTrustedProxies_Resolve(t *testing.T) {
+	p, _ := NewTrustedProxies("10.0.0.0/8")
+	tests := []struct {
+		remoteIP string
+		headers  map[string]string
+		ip       string
+		scheme   string
+		host     string
+	}{
+		// Untrusted remote address.
+		{"1.1.1.1", map[string]string{"X-Forwarded-For": "2.2.2.2", "X-Forwarded-Proto": "https"}, "1.1.1.1", "http", "example.com"},
+		{"10.0.0.1", map[string]string{}, "10.0.0.1", "http", "example.com"},
+		{"10.0.0.1", map[string]string{"X-Forwarded-For": "2.2.2.2", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "foo.com"}, "2.2.2.2", "https", "foo.com"},
+		// The spoofed hops are ignored.
+		{"10.0.0.1", map[string]string{"X-Forwarded-For": "3.3.3.3, 2.2.2.2, 10.0.0.2"}, "2.2.2.2", "http", "example.com"},
+		{"10.0.0.1", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"}, "10.0.0.3", "http", "example.com"},
+		{"10.0.0.1", map[string]string{"X-Forwarded-Proto": "http, HTTPS"}, "10.0.0.1", "https", "example.com"},
+		{"10.0.0.1", map[string]string{"Forwarded": `for=3.3.3.3;proto=http, for="2.2.2.2:4711";proto=https;host=foo.com, for=10.0.0.2`}, "2.2.2.2", "https", "foo.com"},
+		{"10.0.0.1", map[string]string{"Forwarded": `for="[2001:db8::1]:4711"`, "X-Forwarded-For": "2.2.2.2"}, "2001:db8::1", "http", "example.com"},
+		// The obfuscated client.
+		{"10.0.0.1", map[string]string{"Forwarded": `for=_hidden, for=10.0.0.2`}, "10.0.0.2", "http", "example.com"},
+	}
+	for i, test := range tests {
+		f := p.resolve(newProxyRequest(test.remoteIP, test.headers))
+		if f.ip.String() != test.ip || f.scheme != test.scheme || f.host != test.host {
+			t.Errorf("%d: unexpected result %s, %s, %s. Expected %s, %s, %s", i, f.ip, f.scheme, f.host, test.ip, test.scheme, test.host)
+		}
+	}
+}
+
+func TestTrustedProxies_ResolveMultipleLines(t *testing.T) {
+	p, _ := NewTrustedProxies("10.0.0.0/8")
+	tests := []struct {
+		name   string
+		lines  []string
+		ip     string
+		scheme string
+		host   string
+	}{
+		// The first lines are supplied by the client, the last lines are appended by the trusted proxy.
+		{"X-Forwarded-For", []string{"3.3.3.3", "2.2.2.2, 10.0.0.2"}, "2.2.2.2", "http", "example.com"},
+		{"Forwarded", []string{"for=3.3.3.3;host=evil.com", "for=2.2.2.2;proto=https"}, "2.2.2.2", "https", "example.com"},
+		{"X-Forwarded-Proto", []string{"http", "https"}, "10.0.0.1", "https", "example.com"},
+		{"X-Forwarded-Host", []string{"evil.com", "foo.com"}, "10.0.0.1", "http", "foo.com"},
+	}
+	for _, test := range tests {
+		ctx := newProxyRequest("10.0.0.1", nil)
+		for _, line := range test.lines {
+			ctx.Request.Header.Add(test.name, line)
+		}
+		f := p.resolve(ctx)
+		if f.ip.String() != test.ip || f.scheme != test.scheme || f.host != test.host {
+			t.Errorf("%s: unexpected result %s, %s, %s. Expected %s, %s, %s", test.name, f.ip, f.scheme, f.host, test.ip, test.scheme, test.host)
+		}
+	}
+}
+
+func TestParseForwarded(t *testing.T) {
+	elements := parseForwarded(`for="_a,b;c";by=x, For=1.2.3.4;Proto=https`)
+	if len(elements) != 2 || elements[0]["for"] != "_a,b;c" || elements[0]["by"] != "x" || elements[1]["for"] != "1.2.3.4" || elements[1]["proto"] != "https" {
+		t.Errorf("unexpected elements %v", elements)
+	}
+}
+
+func TestContext_RealIP(t *testing.T) {
+	app := NewApplication()
+	p, _ := NewTrustedProxies("10.0.0.0/8")
+	app.SetTrustedProxies(p)
+
+	handler := func(name string) HandlerFunc {
+		return func(ctx *Context) {
+			ctx.Textf("%s %s %s %s", name, ctx.RealIP(), ctx.Scheme(), ctx.RealHost())
+		}
+	}
+	app.NewRouter("").GET("/", handler("default"))
+	app.NewRouter("foo.com").GET("/", handler("foo"))
+
+	ctx := newProxyRequest("10.0.0.1", map[string]string{"X-Forwarded-For": "2.2.2.2", "X-Forwarded-Host": "foo.com:8080", "X-Forwarded-Proto": "https"})
+	ctx.Request.SetRequestURI("/")
+	app.Handler(ctx)
+	if body := string(ctx.Response.Body()); body != "foo 2.2.2.2 https foo.com:8080" {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	ctx = newProxyRequest("1.1.1.1", map[string]string{"X-Forwarded-For": "2.2.2.2", "X-Forwarded-Host": "foo.com"})
+	ctx.Request.SetRequestURI("/")
+	app.Handler(ctx)
+	if body := string(ctx.Response.Body()); body != "default 1.1.1.1 http example.com" {
+		t.Errorf("unexpected body %q", body)
+	}
+}
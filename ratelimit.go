@@ -0,0 +1,363 @@
+package clevergo
+
+import (
+	"errors"
+	"github.com/headwindfly/clevergo/jwt"
+	"github.com/valyala/fasthttp"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRateLimited means that the request exceeds the rate limit.
+	ErrRateLimited = errors.New("rate limit exceeded")
+	// ErrInvalidRateLimit means that the limit, period or window of the rate limiter is not positive.
+	ErrInvalidRateLimit = errors.New("invalid rate limit")
+)
+
+// RateLimitResult is the result of taking a request from the rate limiter.
+type RateLimitResult struct {
+	Allowed    bool          // Whether the request is allowed.
+	Limit      int           // Maximum number of requests in the quota.
+	Remaining  int           // Remaining number of requests in the quota.
+	Reset      time.Duration // Time until the quota is fully restored.
+	RetryAfter time.Duration // Time until the next request would be allowed, zero if it is allowed.
+}
+
+// RateLimiter limits the requests of keys.
+type RateLimiter interface {
+	// Take takes a request of the key.
+	Take(key string) (RateLimitResult, error)
+}
+
+// RateLimitState is the state of a key, the meanings of fields depend on the algorithm.
+type RateLimitState struct {
+	Value    float64
+	Previous float64
+	Time     time.Time
+}
+
+// RateLimitStore stores the states of the rate limiters,
+// the shared backends such as Redis can be used by implementing this interface.
+type RateLimitStore interface {
+	// Update updates the state of key atomically, the state is zero if it doesn't exist,
+	// and it can be removed after ttl.
+	//
+	// The fn may be called more than once, such as retrying on conflicts.
+	Update(key string, ttl time.Duration, fn func(state *RateLimitState)) error
+}
+
+// rateLimitShards is the number of shards of MemoryRateLimitStore.
+const rateLimitShards = 64
+
+// MemoryRateLimitStore is an in-memory RateLimitStore, the keys are distributed
+// into shards to reduce the lock contention, and the expired states are removed periodically.
+type MemoryRateLimitStore struct {
+	shards [rateLimitShards]rateLimitShard
+	now    func() time.Time
+}
+
+type rateLimitShard struct {
+	mu        sync.Mutex
+	states    map[string]*rateLimitEntry
+	lastSweep time.Time
+}
+
+type rateLimitEntry struct {
+	state   RateLimitState
+	expires time.Time
+}
+
+// NewMemoryRateLimitStore returns a MemoryRateLimitStore's instance.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{now: time.Now}
+	for i := range s.shards {
+		s.shards[i].states = make(map[string]*rateLimitEntry)
+	}
+	return s
+}
+
+// Update implemented RateLimitStore Interface.
+func (s *MemoryRateLimitStore) Update(key string, ttl time.Duration, fn func(state *RateLimitState)) error {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	shard := &s.shards[h.Sum32()%rateLimitShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(shard.lastSweep) >= time.Minute {
+		for k, entry := range shard.states {
+			if !now.Before(entry.expires) {
+				delete(shard.states, k)
+			}
+		}
+		shard.lastSweep = now
+	}
+
+	entry, ok := shard.states[key]
+	if !ok || !now.Before(entry.expires) {
+		entry = &rateLimitEntry{}
+		shard.states[key] = entry
+	}
+	fn(&entry.state)
+	entry.expires = now.Add(ttl)
+	return nil
+}
+
+// TokenBucket is a RateLimiter of token bucket algorithm, the bucket holds up to Burst tokens,
+// and it is refilled by Limit tokens per Period, each request takes a token.
+type TokenBucket struct {
+	Limit  int            // Number of tokens refilled per period.
+	Period time.Duration  // Period of refilling.
+	Burst  int            // Capacity of bucket.
+	Store  RateLimitStore // Store of states.
+	now    func() time.Time
+}
+
+// NewTokenBucket returns a TokenBucket's instance with in-memory store,
+// the burst defaults to limit if it is not positive. It panics if the limit or period is not positive.
+func NewTokenBucket(limit int, period time.Duration, burst int) *TokenBucket {
+	if limit <= 0 || period <= 0 {
+		panic("clevergo: " + ErrInvalidRateLimit.Error())
+	}
+	if burst <= 0 {
+		burst = limit
+	}
+	return &TokenBucket{
+		Limit:  limit,
+		Period: period,
+		Burst:  burst,
+		Store:  NewMemoryRateLimitStore(),
+		now:    time.Now,
+	}
+}
+
+// Take implemented RateLimiter Interface.
+func (b *TokenBucket) Take(key string) (RateLimitResult, error) {
+	if b.Limit <= 0 || b.Period <= 0 || b.Burst <= 0 {
+		return RateLimitResult{}, ErrInvalidRateLimit
+	}
+	now := b.now()
+	capacity := float64(b.Burst)
+	rate := float64(b.Limit) / float64(b.Period) // Tokens per nanosecond.
+	ttl := time.Duration(capacity / rate)
+
+	var result RateLimitResult
+	err := b.Store.Update(key, ttl, func(state *RateLimitState) {
+		tokens := capacity
+		if !state.Time.IsZero() {
+			tokens = math.Min(capacity, state.Value+float64(now.Sub(state.Time))*rate)
+		}
+
+		result = RateLimitResult{Limit: b.Burst}
+		if tokens >= 1 {
+			tokens--
+			result.Allowed = true
+		} else {
+			result.RetryAfter = time.Duration(math.Ceil((1 - tokens) / rate))
+		}
+		result.Remaining = int(tokens)
+		result.Reset = time.Duration(math.Ceil((capacity - tokens) / rate))
+
+		state.Value, state.Time = tokens, now
+	})
+	return result, err
+}
+
+// SlidingWindow is a RateLimiter of sliding window algorithm, which allows up to Limit requests
+// in any Window, the requests of previous window are weighted by the overlap of sliding window.
+type SlidingWindow struct {
+	Limit  int            // Maximum number of requests in window.
+	Window time.Duration  // Size of window.
+	Store  RateLimitStore // Store of states.
+	now    func() time.Time
+}
+
+// NewSlidingWindow returns a SlidingWindow's instance with in-memory store,
+// it panics if the limit or window is not positive.
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	if limit <= 0 || window <= 0 {
+		panic("clevergo: " + ErrInvalidRateLimit.Error())
+	}
+	return &SlidingWindow{
+		Limit:  limit,
+		Window: window,
+		Store:  NewMemoryRateLimitStore(),
+		now:    time.Now,
+	}
+}
+
+// Take implemented RateLimiter Interface.
+func (w *SlidingWindow) Take(key string) (RateLimitResult, error) {
+	if w.Limit <= 0 || w.Window <= 0 {
+		return RateLimitResult{}, ErrInvalidRateLimit
+	}
+	now := w.now()
+	start := now.Truncate(w.Window)
+	limit := float64(w.Limit)
+
+	var result RateLimitResult
+	err := w.Store.Update(key, 2*w.Window, func(state *RateLimitState) {
+		// The state holds the counts of current window and previous window.
+		if !state.Time.Equal(start) {
+			if state.Time.Equal(start.Add(-w.Window)) {
+				state.Previous = state.Value
+			} else {
+				state.Previous = 0
+			}
+			state.Value, state.Time = 0, start
+		}
+
+		elapsed := now.Sub(start)
+		weight := 1 - float64(elapsed)/float64(w.Window)
+		count := state.Previous*weight + state.Value
+
+		result = RateLimitResult{Limit: w.Limit}
+		if count+1 <= limit {
+			state.Value++
+			count++
+			result.Allowed = true
+		} else if state.Value+1 > limit || state.Previous == 0 {
+			result.RetryAfter = w.Window - elapsed
+		} else {
+			// Wait until the weighted count of previous window decreases enough.
+			overlap := (limit - 1 - state.Value) / state.Previous
+			result.RetryAfter = time.Duration((1-overlap)*float64(w.Window)) - elapsed
+		}
+		// The quota is fully restored after the counted requests slide out of the window.
+		if state.Value > 0 {
+			result.Reset = 2*w.Window - elapsed
+		} else if state.Previous > 0 {
+			result.Reset = w.Window - elapsed
+		}
+		result.Remaining = int(math.Max(0, math.Floor(limit-count)))
+	})
+	return result, err
+}
+
+// RateLimitConfig for RateLimitMiddleware.
+type RateLimitConfig struct {
+	Limiter RateLimiter               // Rate limiter.
+	KeyFunc func(ctx *Context) string // KeyFunc returns the key of request, empty key means no limit.
+	Headers bool                      // Whether to set the RateLimit-* headers.
+}
+
+// NewRateLimitConfig returns default rate limit configuration, which limits the requests by IP.
+func NewRateLimitConfig(limiter RateLimiter) *RateLimitConfig {
+	return &RateLimitConfig{
+		Limiter: limiter,
+		KeyFunc: RateLimitByIP,
+		Headers: true,
+	}
+}
+
+// RateLimitMiddleware limits the requests by the rate limiter.
+//
+// The rejected requests will be handled by Context.HandleError with status code 429,
+// and the Retry-After header.
+type RateLimitMiddleware struct {
+	config *RateLimitConfig
+}
+
+// NewRateLimitMiddleware returns a RateLimitMiddleware's instance.
+func NewRateLimitMiddleware(config *RateLimitConfig) *RateLimitMiddleware {
+	return &RateLimitMiddleware{config: config}
+}
+
+// Handle implemented Middleware Interface.
+func (m *RateLimitMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		key := m.config.KeyFunc(ctx)
+		if key == "" {
+			next.Handle(ctx)
+			return
+		}
+
+		result, err := m.config.Limiter.Take(key)
+		if err != nil {
+			// Fail open, the unavailable store should not break the application.
+			ctx.Logger().Printf("RateLimit: failed to take request: %s", err)
+			next.Handle(ctx)
+			return
+		}
+
+		if !result.Allowed {
+			ctx.HandleError(fasthttp.StatusTooManyRequests, ErrRateLimited)
+			ctx.Response.Header.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			m.setHeaders(ctx, result)
+			return
+		}
+
+		next.Handle(ctx)
+
+		m.setHeaders(ctx, result)
+	})
+}
+
+// setHeaders sets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers.
+func (m *RateLimitMiddleware) setHeaders(ctx *Context, result RateLimitResult) {
+	if !m.config.Headers {
+		return
+	}
+	ctx.Response.Header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	ctx.Response.Header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	ctx.Response.Header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+}
+
+// ceilSeconds returns the duration in seconds, rounded up.
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// RateLimitByIP limits the requests by client IP.
+//
+// The requests without client IP are not limited, since the clients can't be distinguished.
+func RateLimitByIP(ctx *Context) string {
+	ip := ctx.RealIP()
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// RateLimitByPrincipal limits the requests by the stable identity of the authenticated principal,
+// and by client IP if the request is not authenticated or the principal has no identity.
+//
+// The identity is PrincipalIdentifier.PrincipalID, the subject of JWT claims, or the principal itself if it is a string,
+// so that the new tokens of the same user share the quota.
+func RateLimitByPrincipal(ctx *Context) string {
+	if id := principalID(ctx.Principal()); id != "" {
+		return "principal:" + id
+	}
+	return RateLimitByIP(ctx)
+}
+
+func principalID(principal interface{}) string {
+	switch p := principal.(type) {
+	case PrincipalIdentifier:
+		return p.PrincipalID()
+	case jwt.Claims:
+		return p.Registered().Subject
+	case string:
+		return p
+	}
+	return ""
+}
+
+// RateLimitByRoute limits the requests by the matched route and client IP,
+// so that each route has its own quota.
+func RateLimitByRoute(ctx *Context) string {
+	ip := RateLimitByIP(ctx)
+	if ip == "" {
+		return ""
+	}
+	return string(ctx.Method()) + " " + ctx.Route() + " " + ip
+}
@@ -0,0 +1,277 @@
+package clevergo
+
+import (
+	"errors"
+	"github.com/headwindfly/clevergo/jwt"
+	"github.com/valyala/fasthttp"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	b := NewTokenBucket(1, time.Second, 3)
+	b.now = clock.Now
+	b.Store.(*MemoryRateLimitStore).now = clock.Now
+
+	for i := 0; i < 3; i++ {
+		result, err := b.Take("foo")
+		if err != nil || !result.Allowed || result.Remaining != 2-i || result.Limit != 3 {
+			t.Fatalf("%d: unexpected result %+v, %v", i, result, err)
+		}
+	}
+	result, _ := b.Take("foo")
+	if result.Allowed || result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// The other keys are not affected.
+	if result, _ = b.Take("bar"); !result.Allowed {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	clock.now = clock.now.Add(1500 * time.Millisecond)
+	if result, _ = b.Take("foo"); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result, _ = b.Take("foo"); result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// The bucket is full after a long time.
+	clock.now = clock.now.Add(time.Hour)
+	if result, _ = b.Take("foo"); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestRateLimiter_Invalid(t *testing.T) {
+	limiters := []RateLimiter{
+		&TokenBucket{Period: time.Second, Burst: 1},
+		&TokenBucket{Limit: 1, Burst: 1},
+		&SlidingWindow{Limit: 0, Window: time.Minute},
+		&SlidingWindow{Limit: 1},
+	}
+	for i, limiter := range limiters {
+		if _, err := limiter.Take("foo"); err != ErrInvalidRateLimit {
+			t.Errorf("%d: unexpected error %v", i, err)
+		}
+	}
+
+	for i, fn := range []func(){
+		func() { NewTokenBucket(0, time.Second, 1) },
+		func() { NewSlidingWindow(1, 0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%d: expected panic of invalid rate limit", i)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+type identifiedPrincipal struct {
+	id      string
+	session string
+}
+
+func (p *identifiedPrincipal) PrincipalID() string {
+	return p.id
+}
+
+func TestRateLimitByPrincipal(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		principal interface{}
+		key       string
+	}{
+		{nil, "0.0.0.0"},
+		{"alice", "principal:alice"},
+		{&identifiedPrincipal{id: "42", session: "abc"}, "principal:42"},
+		// The key doesn't depend on the expiration and ID of token.
+		{&jwt.RegisteredClaims{Subject: "alice", ID: "abc", ExpiresAt: jwt.NewNumericDate(now)}, "principal:alice"},
+		{&jwt.RegisteredClaims{Subject: "alice", ID: "def", ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour))}, "principal:alice"},
+		{&jwt.RegisteredClaims{ID: "abc"}, "0.0.0.0"},
+		{struct{ Name string }{"alice"}, "0.0.0.0"},
+	}
+	for i, test := range tests {
+		principal := test.principal
+		r := NewRouter()
+		r.GET("/", HandlerFunc(func(ctx *Context) {
+			ctx.SetPrincipal(principal)
+			ctx.Text(RateLimitByPrincipal(ctx))
+		}))
+		resp := serve(t, r.Handler, "GET / HTTP/1.1\r\n\r\n")
+		if key := string(resp.Body()); key != test.key {
+			t.Errorf("%d: unexpected key %q. Expected %q", i, key, test.key)
+		}
+	}
+}
+
+func TestRateLimitByIP_NoIP(t *testing.T) {
+	req := &fasthttp.RequestCtx{}
+	req.Init(&fasthttp.Request{}, &net.TCPAddr{}, nil)
+	ctx := NewContext(NewRouter(), req, nil)
+	defer ctx.Close()
+	for name, keyFunc := range map[string]func(*Context) string{
+		"ip":        RateLimitByIP,
+		"principal": RateLimitByPrincipal,
+		"route":     RateLimitByRoute,
+	} {
+		if key := keyFunc(ctx); key != "" {
+			t.Errorf("%s: unexpected key %q", name, key)
+		}
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := &testClock{now: time.Unix(1599999960, 0)}
+	w := NewSlidingWindow(4, time.Minute)
+	w.now = clock.Now
+	w.Store.(*MemoryRateLimitStore).now = clock.Now
+
+	for i := 0; i < 4; i++ {
+		result, err := w.Take("foo")
+		if err != nil || !result.Allowed || result.Remaining != 3-i {
+			t.Fatalf("%d: unexpected result %+v, %v", i, result, err)
+		}
+	}
+	result, _ := w.Take("foo")
+	if result.Allowed || result.RetryAfter != time.Minute {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// A quarter of next window, the previous requests are weighted by 0.75.
+	clock.now = clock.now.Add(75 * time.Second)
+	if result, _ = w.Take("foo"); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	// 4*0.75+1 = 4, the next request is allowed when the weight decreases to 0.5.
+	if result, _ = w.Take("foo"); result.Allowed || result.RetryAfter != 15*time.Second {
+		t.Errorf("unexpected result %+v", result)
+	}
+	clock.now = clock.now.Add(15 * time.Second)
+	if result, _ = w.Take("foo"); !result.Allowed {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// The previous window is dropped if it is not adjacent.
+	clock.now = clock.now.Add(2 * time.Minute)
+	if result, _ = w.Take("foo"); !result.Allowed || result.Remaining != 3 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	s := NewMemoryRateLimitStore()
+	s.now = clock.Now
+
+	increase := func(state *RateLimitState) {
+		state.Value++
+	}
+	var value float64
+	get := func(state *RateLimitState) {
+		value = state.Value
+	}
+
+	s.Update("foo", time.Minute, increase)
+	s.Update("foo", time.Minute, get)
+	if value != 1 {
+		t.Errorf("unexpected value %v", value)
+	}
+
+	// The expired states are reset, and removed by sweeping.
+	clock.now = clock.now.Add(2 * time.Minute)
+	s.Update("foo", time.Minute, get)
+	if value != 0 {
+		t.Errorf("unexpected value %v", value)
+	}
+	s.Update("bar", time.Second, increase)
+	clock.now = clock.now.Add(2 * time.Minute)
+	shard := func(key string) *rateLimitShard {
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		return &s.shards[h.Sum32()%rateLimitShards]
+	}
+	// Find another key of the same shard.
+	key := "baz"
+	for i := 0; shard(key) != shard("bar"); i++ {
+		key = "baz" + strconv.Itoa(i)
+	}
+	s.Update(key, time.Minute, increase)
+	if _, ok := shard("bar").states["bar"]; ok {
+		t.Error("expected the expired state to be removed")
+	}
+}
+
+type failingRateLimiter struct{}
+
+func (failingRateLimiter) Take(key string) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store unavailable")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter := NewTokenBucket(1, time.Hour, 2)
+	config := NewRateLimitConfig(limiter)
+	config.KeyFunc = RateLimitByRoute
+	r := NewRouter()
+	r.AddMiddleware(NewRateLimitMiddleware(config))
+	r.GET("/users/:id", HandlerFunc(func(ctx *Context) {
+		ctx.Text("OK")
+	}))
+	r.GET("/posts", HandlerFunc(func(ctx *Context) {
+		ctx.Text("OK")
+	}))
+
+	tests := []struct {
+		path       string
+		code       int
+		remaining  string
+		retryAfter string
+	}{
+		{"/users/1", 200, "1", ""},
+		{"/users/2", 200, "0", ""},
+		{"/users/3", 429, "0", "3600"},
+		{"/posts", 200, "1", ""},
+	}
+	for _, test := range tests {
+		resp := serve(t, r.Handler, "GET "+test.path+" HTTP/1.1\r\n\r\n")
+		if resp.StatusCode() != test.code {
+			t.Errorf("%s: unexpected status code %d. Expected %d", test.path, resp.StatusCode(), test.code)
+		}
+		if v := string(resp.Header.Peek("RateLimit-Limit")); v != "2" {
+			t.Errorf("%s: unexpected RateLimit-Limit %q", test.path, v)
+		}
+		if v := string(resp.Header.Peek("RateLimit-Remaining")); v != test.remaining {
+			t.Errorf("%s: unexpected RateLimit-Remaining %q. Expected %q", test.path, v, test.remaining)
+		}
+		if v := string(resp.Header.Peek("Retry-After")); v != test.retryAfter {
+			t.Errorf("%s: unexpected Retry-After %q. Expected %q", test.path, v, test.retryAfter)
+		}
+	}
+
+	// Fail open.
+	r = NewRouter()
+	r.AddMiddleware(NewRateLimitMiddleware(NewRateLimitConfig(failingRateLimiter{})))
+	r.GET("/", HandlerFunc(func(ctx *Context) {
+		ctx.Text("OK")
+	}))
+	if resp := serve(t, r.Handler, "GET / HTTP/1.1\r\n\r\n"); resp.StatusCode() != 200 {
+		t.Errorf("unexpected status code %d", resp.StatusCode())
+	}
+}
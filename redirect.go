@@ -0,0 +1,57 @@
+package clevergo
+
+import (
+	"github.com/valyala/fasthttp"
+	"net/url"
+	"strings"
+)
+
+// Redirect redirects the request to the url with the status code, such as 301, 302, 303, 307 and 308,
+// the other codes are replaced by 302.
+//
+// It shadows fasthttp.RequestCtx.Redirect(uri, statusCode), which is still available as ctx.RequestCtx.Redirect.
+func (ctx *Context) Redirect(code int, url string) {
+	ctx.checkReleased()
+	ctx.RequestCtx.Redirect(url, code)
+}
+
+// RedirectToRoute redirects the request to the named route with 302, see Router.Name.
+//
+// The errors of building URL are handled by Context.HandleError with status code 500.
+func (ctx *Context) RedirectToRoute(name string, params map[string]string) {
+	ctx.checkReleased()
+	u, err := ctx.router.URL(name, params)
+	if err != nil {
+		ctx.HandleError(fasthttp.StatusInternalServerError, err)
+		return
+	}
+	ctx.Redirect(fasthttp.StatusFound, u)
+}
+
+// RedirectBack redirects the request to the Referer, such as the form's page after submitting.
+// It redirects to the fallback, or "/" if no fallback given, if the Referer is missing or it is from
+// other hosts, so that it can not be used as an open redirect.
+//
+// The status code is 302 for GET and HEAD requests, and 303 for the others.
+func (ctx *Context) RedirectBack(fallback ...string) {
+	ctx.checkReleased()
+	target := "/"
+	if len(fallback) > 0 {
+		target = fallback[0]
+	}
+	if referer := ctx.Request.Header.Referer(); len(referer) > 0 {
+		if u, err := url.Parse(string(referer)); err == nil && u.Host == ctx.RealHost() &&
+			(u.Scheme == "http" || u.Scheme == "https") {
+			// The paths like "//evil.com" and "/\evil.com" are resolved as other hosts by the clients.
+			if !strings.HasPrefix(u.Path, "//") && !strings.HasPrefix(u.Path, "/\\") {
+				target = u.RequestURI()
+			}
+		}
+	}
+
+	code := fasthttp.StatusSeeOther
+	if ctx.IsGet() || ctx.IsHead() {
+		code = fasthttp.StatusFound
+	}
+	ctx.Redirect(code, target)
+}
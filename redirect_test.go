@@ -0,0 +1,75 @@
+package clevergo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRouter_URL(t *testing.T) {
+	r := NewRouter()
+	r.GET("/users/:id<int>", HandlerFunc(func(ctx *Context) {}))
+	r.Name("users.show", "/users/:id<int>")
+	r.Group("/files").Name("files", "/*path")
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		url    string
+		err    error
+	}{
+		{"users.show", map[string]string{"id": "42"}, "/users/42", nil},
+		{"users.show", map[string]string{"id": "foo"}, "", ErrParamConstraint},
+		{"users.show", nil, "", ErrParamMissing},
+		{"files", map[string]string{"path": "/a b/c.txt"}, "/files/a%20b/c.txt", nil},
+		{"missing", nil, "", ErrRouteNotFound},
+	}
+	for _, test := range tests {
+		u, err := r.URL(test.name, test.params)
+		if u != test.url || !errors.Is(err, test.err) {
+			t.Errorf("URL(%q, %v) = %q, %v, expect %q, %v", test.name, test.params, u, err, test.url, test.err)
+		}
+	}
+
+	if routes := r.Routes(); routes[0] != (Route{Method: "GET", Path: "/users/:id<int>", Name: "users.show"}) {
+		t.Errorf("unexpected routes %v", routes)
+	}
+}
+
+func TestContext_Redirect(t *testing.T) {
+	r := NewRouter()
+	r.Name("users.show", "/users/:id")
+	r.GET("/redirect", HandlerFunc(func(ctx *Context) {
+		ctx.Redirect(301, "/new")
+	}))
+	r.GET("/route", HandlerFunc(func(ctx *Context) {
+		ctx.RedirectToRoute("users.show", map[string]string{"id": "1"})
+	}))
+	r.GET("/broken", HandlerFunc(func(ctx *Context) {
+		ctx.RedirectToRoute("users.show", nil)
+	}))
+	r.POST("/back", HandlerFunc(func(ctx *Context) {
+		ctx.RedirectBack("/home")
+	}))
+
+	tests := []struct {
+		request  string
+		code     int
+		location string
+	}{
+		{"GET /redirect HTTP/1.1\r\nHost: example.com\r\n\r\n", 301, "http://example.com/new"},
+		{"GET /route HTTP/1.1\r\nHost: example.com\r\n\r\n", 302, "http://example.com/users/1"},
+		{"GET /broken HTTP/1.1\r\nHost: example.com\r\n\r\n", 500, ""},
+		{"POST /back HTTP/1.1\r\nHost: example.com\r\nReferer: http://example.com/form?a=1\r\n\r\n", 303, "http://example.com/form?a=1"},
+		{"POST /back HTTP/1.1\r\nHost: example.com\r\nReferer: http://evil.com/form\r\n\r\n", 303, "http://example.com/home"},
+		// The protocol-relative paths of the same host.
+		{"POST /back HTTP/1.1\r\nHost: example.com\r\nReferer: http://example.com//evil.com/a\r\n\r\n", 303, "http://example.com/home"},
+		{"POST /back HTTP/1.1\r\nHost: example.com\r\nReferer: http://example.com/\\evil.com/a\r\n\r\n", 303, "http://example.com/home"},
+		{"POST /back HTTP/1.1\r\nHost: example.com\r\n\r\n", 303, "http://example.com/home"},
+	}
+	for i, test := range tests {
+		resp := serve(t, r.Handler, test.request)
+		if resp.StatusCode() != test.code || string(resp.Header.Peek("Location")) != test.location {
+			t.Errorf("%d: unexpected response %d %q, expect %d %q", i, resp.StatusCode(), resp.Header.Peek("Location"), test.code, test.location)
+		}
+	}
+}
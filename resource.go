@@ -0,0 +1,297 @@
+package clevergo
+
+import (
+	"github.com/clevergo/router"
+	"github.com/valyala/fasthttp"
+	"strings"
+)
+
+// IndexHandler is implemented by the resource controllers which list the resources, such as GET /photos.
+type IndexHandler interface {
+	Index(ctx *Context)
+}
+
+// NewHandler is implemented by the resource controllers which return the form of creating a resource,
+// such as GET /photos/new.
+type NewHandler interface {
+	New(ctx *Context)
+}
+
+// CreateHandler is implemented by the resource controllers which create a resource, such as POST /photos.
+type CreateHandler interface {
+	Create(ctx *Context)
+}
+
+// ShowHandler is implemented by the resource controllers which show a resource, such as GET /photos/:id.
+type ShowHandler interface {
+	Show(ctx *Context)
+}
+
+// EditHandler is implemented by the resource controllers which return the form of editing a resource,
+// such as GET /photos/:id/edit.
+type EditHandler interface {
+	Edit(ctx *Context)
+}
+
+// UpdateHandler is implemented by the resource controllers which update a resource,
+// such as PUT /photos/:id and PATCH /photos/:id.
+type UpdateHandler interface {
+	Update(ctx *Context)
+}
+
+// DeleteHandler is implemented by the resource controllers which delete a resource, such as DELETE /photos/:id.
+type DeleteHandler interface {
+	Delete(ctx *Context)
+}
+
+// resourceActions are the conventional actions in order of registration.
+var resourceActions = []struct {
+	name   string
+	method string
+	member bool   // Whether the action is on the member path.
+	suffix string // Suffix of path.
+}{
+	{"Index", "GET", false, ""},
+	{"New", "GET", false, "/new"},
+	{"Create", "POST", false, ""},
+	{"Show", "GET", true, ""},
+	{"Edit", "GET", true, "/edit"},
+	{"Update", "PUT", true, ""},
+	{"Update", "PATCH", true, ""},
+	{"Delete", "DELETE", true, ""},
+}
+
+// resourceHandler returns the request handler of the action, or nil if the controller doesn't implement it.
+func resourceHandler(c ControllerInterface, action string) Handler {
+	var handler func(*Context)
+	switch action {
+	case "Index":
+		if h, ok := c.(IndexHandler); ok {
+			handler = h.Index
+		}
+	case "New":
+		if h, ok := c.(NewHandler); ok {
+			handler = h.New
+		}
+	case "Create":
+		if h, ok := c.(CreateHandler); ok {
+			handler = h.Create
+		}
+	case "Show":
+		if h, ok := c.(ShowHandler); ok {
+			handler = h.Show
+		}
+	case "Edit":
+		if h, ok := c.(EditHandler); ok {
+			handler = h.Edit
+		}
+	case "Update":
+		if h, ok := c.(UpdateHandler); ok {
+			handler = h.Update
+		}
+	case "Delete":
+		if h, ok := c.(DeleteHandler); ok {
+			handler = h.Delete
+		}
+	}
+	if handler == nil {
+		return nil
+	}
+	return HandlerFunc(handler)
+}
+
+// ResourceConfig for registering resource controller.
+type ResourceConfig struct {
+	// Param is the member param with optional constraint, such as "id" and "id<int>".
+	// The empty param of nested resource is derived from its path, see Resource.ResourceWithConfig.
+	Param string
+	// Only registers the specified actions only, such as "Index" and "Show", empty means all actions.
+	Only []string
+	// Except excludes the specified actions.
+	Except []string
+}
+
+// NewResourceConfig returns default resource configuration, the member param is "id".
+func NewResourceConfig() *ResourceConfig {
+	return &ResourceConfig{
+		Param: "id",
+	}
+}
+
+func (config *ResourceConfig) allows(action string) bool {
+	if len(config.Only) > 0 && !containsFold(config.Only, action) {
+		return false
+	}
+	return !containsFold(config.Except, action)
+}
+
+// Resource is a registered resource, which registers the nested resources under its member path.
+type Resource struct {
+	router *Router
+	path   string // Collection path, such as "/photos".
+	member string // Member path, such as "/photos/:id".
+}
+
+// Path returns the collection path, such as "/photos".
+func (res *Resource) Path() string {
+	return res.path
+}
+
+// MemberPath returns the member path, such as "/photos/:id".
+func (res *Resource) MemberPath() string {
+	return res.member
+}
+
+// Resource registers the nested resource controller with default configuration,
+// such as "/photos/:id/comments/:comment_id", see ResourceWithConfig.
+func (res *Resource) Resource(path string, c ControllerInterface) *Resource {
+	config := NewResourceConfig()
+	config.Param = ""
+	return res.ResourceWithConfig(path, c, config)
+}
+
+// ResourceWithConfig registers the nested resource controller with the configuration.
+//
+// If the param of configuration is empty and the "id" is used by the parent resource,
+// the param is derived from the last segment of path, such as "comment_id" of "/comments".
+func (res *Resource) ResourceWithConfig(path string, c ControllerInterface, config *ResourceConfig) *Resource {
+	if config.Param == "" && hasParam(res.member, "id") {
+		derived := *config
+		derived.Param = resourceParam(path)
+		config = &derived
+	}
+	return res.router.ResourceWithConfig(res.member+path, c, config)
+}
+
+// Resource registers the resource controller with default configuration, see ResourceWithConfig.
+//
+// The member param is "id", the nested resources registered by Resource.Resource use
+// a param derived from their paths, such as "/photos/:id/comments/:comment_id".
+func (r *Router) Resource(path string, c ControllerInterface) *Resource {
+	return r.ResourceWithConfig(path, c, NewResourceConfig())
+}
+
+// ResourceWithConfig registers the conventional routes of the actions which the resource controller implements,
+// see IndexHandler, ShowHandler etc:
+//
+//	GET    /photos          Index
+//	GET    /photos/new      New
+//	POST   /photos          Create
+//	GET    /photos/:id      Show
+//	GET    /photos/:id/edit Edit
+//	PUT    /photos/:id      Update
+//	PATCH  /photos/:id      Update
+//	DELETE /photos/:id      Delete
+//
+// The request handlers are wrapped by the controller's Handle once, and then the router's middlewares.
+// The requirements declared by ControllerRequirements are keyed by action, such as "Create".
+// It panics if the member param is already used by the path.
+func (r *Router) ResourceWithConfig(path string, c ControllerInterface, config *ResourceConfig) *Resource {
+	param := config.Param
+	if param == "" {
+		param = "id"
+	}
+	name := param
+	if i := strings.IndexByte(name, '<'); i >= 0 {
+		name = name[:i]
+	}
+	if hasParam(path, name) {
+		panic("clevergo: param " + name + " is already used by resource path " + path)
+	}
+	res := &Resource{
+		router: r,
+		path:   path,
+		member: path + "/:" + param,
+	}
+
+	var requirements map[string]Requirement
+	if cr, ok := c.(ControllerRequirements); ok {
+		requirements = cr.Requirements()
+	}
+
+	var newHandler, showHandler Handler
+	for _, action := range resourceActions {
+		handler := resourceHandler(c, action.name)
+		if handler == nil || !config.allows(action.name) {
+			continue
+		}
+		if requirement, ok := requirements[action.name]; ok {
+			handler = NewAuthorizeMiddleware(requirement).Handle(handler)
+		}
+		handler = c.Handle(handler)
+
+		route := res.path
+		if action.member {
+			route = res.member
+		}
+		route += action.suffix
+		switch action.name {
+		case "New":
+			newHandler = handler
+			r.addRoute(action.method, route)
+		case "Show":
+			showHandler = handler
+			r.addRoute(action.method, route)
+		default:
+			r.Handle(action.method, route, handler)
+		}
+	}
+	if newHandler != nil || showHandler != nil {
+		r.Router.Handle("GET", compileRoute(res.member).path, r.memberHandler(res, name, newHandler, showHandler))
+	}
+
+	return res
+}
+
+// memberHandler returns the handler of GET member path, which dispatches the path "/photos/new" to New,
+// since it can not be registered beside "/photos/:id".
+func (r *Router) memberHandler(res *Resource, name string, newHandler, showHandler Handler) router.Handle {
+	var show router.Handle
+	if showHandler != nil {
+		show = r.getHandler(res.member, showHandler)
+	} else {
+		notFound := r.getHandler(res.member, HandlerFunc(func(ctx *Context) {
+			ctx.HandleError(fasthttp.StatusNotFound, ErrNotFound)
+		}))
+		// The member path is registered for New only, it may be answered by the other methods.
+		show = func(ctx *fasthttp.RequestCtx, ps router.Params) {
+			if !r.handleMethods(ctx) {
+				notFound(ctx, ps)
+			}
+		}
+	}
+	if newHandler == nil {
+		return show
+	}
+	form := r.getHandler(res.path+"/new", newHandler)
+	return func(ctx *fasthttp.RequestCtx, ps router.Params) {
+		if ps.ByName(name) == "new" {
+			form(ctx, ps)
+			return
+		}
+		show(ctx, ps)
+	}
+}
+
+// hasParam reports whether the param is used by the path, such as "id" of "/photos/:id<int>".
+func hasParam(path, name string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") && strings.SplitN(segment[1:], "<", 2)[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceParam returns the member param derived from the last segment of path,
+// such as "comment_id" of "/comments" and "category_id" of "/categories".
+func resourceParam(path string) string {
+	name := path[strings.LastIndexByte(path, '/')+1:]
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		name = name[:len(name)-3] + "y"
+	case strings.HasSuffix(name, "s"):
+		name = name[:len(name)-1]
+	}
+	return name + "_id"
+}
@@ -0,0 +1,167 @@
+package clevergo
+
+import (
+	"reflect"
+	"testing"
+)
+
+type photoController struct {
+	Controller
+}
+
+func (c photoController) Index(ctx *Context) {
+	ctx.Text("Index")
+}
+
+func (c photoController) New(ctx *Context) {
+	ctx.Text("New")
+}
+
+func (c photoController) Create(ctx *Context) {
+	ctx.Text("Create")
+}
+
+func (c photoController) Show(ctx *Context) {
+	ctx.Text("Show " + ctx.Param("photo_id"))
+}
+
+func (c photoController) Edit(ctx *Context) {
+	ctx.Text("Edit " + ctx.Param("photo_id"))
+}
+
+func (c photoController) Update(ctx *Context) {
+	ctx.Text("Update " + ctx.Param("photo_id"))
+}
+
+func (c photoController) Delete(ctx *Context) {
+	ctx.Text("Delete " + ctx.Param("photo_id"))
+}
+
+// commentController implements Index, Show and Delete only.
+type commentController struct {
+	Controller
+}
+
+func (c commentController) Index(ctx *Context) {
+	ctx.Text("Comments of " + ctx.Param("photo_id"))
+}
+
+func (c commentController) Show(ctx *Context) {
+	ctx.Text("Comment " + ctx.Param("id") + " of " + ctx.Param("photo_id"))
+}
+
+func (c commentController) Delete(ctx *Context) {
+	ctx.Text("Delete comment " + ctx.Param("id"))
+}
+
+func TestRouter_Resource(t *testing.T) {
+	r := NewRouter()
+	photos := &photoController{}
+	photos.AddMiddleware(simpleMiddleware{})
+	res := r.ResourceWithConfig("/photos", photos, &ResourceConfig{Param: "photo_id<int>", Except: []string{"edit"}})
+	comments := res.ResourceWithConfig("/comments", commentController{}, &ResourceConfig{Only: []string{"Index", "Show"}})
+	if res.MemberPath() != "/photos/:photo_id<int>" || comments.Path() != "/photos/:photo_id<int>/comments" {
+		t.Errorf("Unexpected paths %q, %q", res.MemberPath(), comments.Path())
+	}
+
+	expected := []Route{
+		{Method: "GET", Path: "/photos"},
+		{Method: "GET", Path: "/photos/new"},
+		{Method: "POST", Path: "/photos"},
+		{Method: "GET", Path: "/photos/:photo_id<int>"},
+		{Method: "PUT", Path: "/photos/:photo_id<int>"},
+		{Method: "PATCH", Path: "/photos/:photo_id<int>"},
+		{Method: "DELETE", Path: "/photos/:photo_id<int>"},
+		{Method: "GET", Path: "/photos/:photo_id<int>/comments"},
+		{Method: "GET", Path: "/photos/:photo_id<int>/comments/:id"},
+	}
+	if routes := r.Routes(); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("Unexpected routes %v", routes)
+	}
+
+	tests := []struct {
+		request string
+		code    int
+		body    string
+		allow   string
+	}{
+		{"GET /photos HTTP/1.1\r\n\r\n", 200, "Index", ""},
+		{"GET /photos/new HTTP/1.1\r\n\r\n", 200, "New", ""},
+		{"POST /photos HTTP/1.1\r\n\r\n", 200, "Create", ""},
+		{"GET /photos/1 HTTP/1.1\r\n\r\n", 200, "Show 1", ""},
+		{"PUT /photos/1 HTTP/1.1\r\n\r\n", 200, "Update 1", ""},
+		{"PATCH /photos/1 HTTP/1.1\r\n\r\n", 200, "Update 1", ""},
+		{"DELETE /photos/1 HTTP/1.1\r\n\r\n", 200, "Delete 1", ""},
+		{"GET /photos/abc HTTP/1.1\r\n\r\n", 404, "", ""},
+		{"GET /photos/1/edit HTTP/1.1\r\n\r\n", 404, "", ""},
+		{"DELETE /photos HTTP/1.1\r\n\r\n", 405, "", "GET, HEAD, POST, OPTIONS"},
+		{"GET /photos/1/comments HTTP/1.1\r\n\r\n", 200, "Comments of 1", ""},
+		{"GET /photos/1/comments/2 HTTP/1.1\r\n\r\n", 200, "Comment 2 of 1", ""},
+		{"DELETE /photos/1/comments/2 HTTP/1.1\r\n\r\n", 405, "", "GET, HEAD, OPTIONS"},
+	}
+	for _, test := range tests {
+		resp := serve(t, r.Handler, test.request)
+		if resp.StatusCode() != test.code {
+			t.Errorf("%q: unexpected status code %d. Expected %d", test.request, resp.StatusCode(), test.code)
+			continue
+		}
+		if test.code == 200 && string(resp.Body()) != test.body {
+			t.Errorf("%q: unexpected body %q. Expected %q", test.request, resp.Body(), test.body)
+		}
+		if allow := string(resp.Header.Peek("Allow")); allow != test.allow {
+			t.Errorf("%q: unexpected Allow %q. Expected %q", test.request, allow, test.allow)
+		}
+	}
+
+	// The controller's middlewares wrap the handlers once.
+	for _, request := range []string{"GET /photos/new HTTP/1.1\r\n\r\n", "GET /photos/1 HTTP/1.1\r\n\r\n"} {
+		resp := serve(t, r.Handler, request)
+		if n := len(resp.Header.PeekAll("Middleware")); n != 1 {
+			t.Errorf("%q: the middleware is applied %d times", request, n)
+		}
+	}
+}
+
+func TestRouter_ResourceNewOnly(t *testing.T) {
+	r := NewRouter()
+	r.ResourceWithConfig("/photos", photoController{}, &ResourceConfig{Only: []string{"New", "Update"}})
+
+	if resp := serve(t, r.Handler, "GET /photos/new HTTP/1.1\r\n\r\n"); string(resp.Body()) != "New" {
+		t.Errorf("Unexpected body %q", resp.Body())
+	}
+	resp := serve(t, r.Handler, "GET /photos/1 HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 405 {
+		t.Errorf("Unexpected status code %d. Expected %d", resp.StatusCode(), 405)
+	}
+	if allow := string(resp.Header.Peek("Allow")); allow != "PUT, PATCH, OPTIONS" {
+		t.Errorf("Unexpected Allow %q", allow)
+	}
+}
+
+func TestRouter_ResourceNestedParam(t *testing.T) {
+	r := NewRouter()
+	comments := r.Resource("/photos", photoController{}).Resource("/comments", commentController{})
+	if comments.MemberPath() != "/photos/:id/comments/:comment_id" {
+		t.Errorf("Unexpected member path %q", comments.MemberPath())
+	}
+	categories := r.ResourceWithConfig("/albums", photoController{}, &ResourceConfig{Param: "id<int>"}).
+		ResourceWithConfig("/categories", commentController{}, &ResourceConfig{})
+	if categories.MemberPath() != "/albums/:id<int>/categories/:category_id" {
+		t.Errorf("Unexpected member path %q", categories.MemberPath())
+	}
+
+	resp := serve(t, r.Handler, "GET /photos/1/comments/2 HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 200 {
+		t.Errorf("Unexpected status code %d", resp.StatusCode())
+	}
+}
+
+func TestRouter_ResourceDuplicateParam(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected panic of duplicate param")
+		}
+	}()
+	r := NewRouter()
+	r.Resource("/photos", photoController{}).ResourceWithConfig("/comments", commentController{}, NewResourceConfig())
+}
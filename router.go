@@ -1,25 +1,58 @@
 package clevergo
 
 import (
+	"errors"
 	"github.com/clevergo/router"
 	"github.com/clevergo/sessions"
 	"github.com/valyala/fasthttp"
+	"net/url"
+	"strings"
 )
 
+var (
+	// ErrRouteNotFound means that there is no route of the name.
+	ErrRouteNotFound = errors.New("route not found")
+	// ErrParamConstraint means that the param doesn't satisfy the constraint of route.
+	ErrParamConstraint = errors.New("param doesn't satisfy the constraint")
+	// ErrMethodNotAllowed means that the request method is not registered for the request path.
+	ErrMethodNotAllowed = errors.New("method not allowed")
+)
+
+// Route contains the method, path and name of a registered request handler.
+type Route struct {
+	Method string
+	Path   string
+	Name   string
+}
+
 // Router for managing request handlers.
 type Router struct {
 	*router.Router
-	middlewares  []Middleware    // Middlewares.
-	sessionStore sessions.Store  // Session store for Context.
-	logger       fasthttp.Logger // Logger for Context.
+	middlewares    []Middleware      // Middlewares.
+	sessionStore   sessions.Store    // Session store for Context.
+	logger         fasthttp.Logger   // Logger for Context.
+	errorHandler   ErrorHandler      // Error handler for Context.
+	policy         Policy            // Authorization policy for Context.
+	trustedProxies *TrustedProxies   // Trusted proxies for Context.
+	routes         []Route           // Registered routes.
+	routeNames     map[string]string // Paths of the named routes.
+	cookieKeys     []cookieKey       // Keys of signed and encrypted cookies.
+	templates      templateMasters   // Unexecuted clones of the rendered templates.
 }
 
+// ErrorHandler handles the error with HTTP status code.
+type ErrorHandler func(ctx *Context, code int, err error)
+
 // NewRouter returns a Router's instance.
 func NewRouter() *Router {
-	return &Router{
+	r := &Router{
 		Router:      router.New(),
 		middlewares: make([]Middleware, 0),
 	}
+	// The methods not allowed and OPTIONS requests are answered by Router.Handler, which respects the constraints.
+	r.Router.HandleMethodNotAllowed = false
+	r.Router.HandleOPTIONS = false
+	return r
 }
 
 // SetSessionStore set session store.
@@ -32,6 +65,33 @@ func (r *Router) SetLogger(logger fasthttp.Logger) {
 	r.logger = logger
 }
 
+// SetErrorHandler set error handler.
+func (r *Router) SetErrorHandler(handler ErrorHandler) {
+	r.errorHandler = handler
+}
+
+// SetPolicy set authorization policy.
+func (r *Router) SetPolicy(policy Policy) {
+	r.policy = policy
+}
+
+// SetTrustedProxies set trusted proxies.
+func (r *Router) SetTrustedProxies(proxies *TrustedProxies) {
+	r.trustedProxies = proxies
+}
+
+// SetSecretKeys set secret keys of signed and encrypted cookies.
+//
+// The cookies are always encoded by the first key, and they are decoded by trying all keys in order,
+// so that the keys can be rotated by prepending the new key. It panics if any of the keys is empty.
+func (r *Router) SetSecretKeys(keys ...[]byte) {
+	cookieKeys := make([]cookieKey, len(keys))
+	for i, key := range keys {
+		cookieKeys[i] = newCookieKey(key)
+	}
+	r.cookieKeys = cookieKeys
+}
+
 // SetMiddlewares set middlewares.
 func (r *Router) SetMiddlewares(middlewares []Middleware) {
 	r.middlewares = middlewares
@@ -79,74 +139,278 @@ func (r *Router) DELETE(path string, handler Handler) {
 
 // Handle register custom METHOD request handler.
 func (r *Router) Handle(method, path string, handler Handler) {
-	r.Router.Handle(method, path, r.getHandler(handler))
+	r.Router.Handle(method, compileRoute(path).path, r.getHandler(path, handler))
+	r.addRoute(method, path)
+}
+
+// Routes returns the registered routes in order of registration.
+func (r *Router) Routes() []Route {
+	routes := make([]Route, len(r.routes))
+	copy(routes, r.routes)
+	return routes
+}
+
+func (r *Router) addRoute(method, path string) {
+	route := Route{Method: method, Path: path}
+	for name, p := range r.routeNames {
+		if p == path {
+			route.Name = name
+		}
+	}
+	r.routes = append(r.routes, route)
+}
+
+// Name names the routes of the path, such as "/users/:id<int>", so that their URL can be built by Router.URL.
+// The routes can be named before or after registration.
+func (r *Router) Name(name, path string) {
+	if r.routeNames == nil {
+		r.routeNames = make(map[string]string)
+	}
+	r.routeNames[name] = path
+	for i := range r.routes {
+		if r.routes[i].Path == path {
+			r.routes[i].Name = name
+		} else if r.routes[i].Name == name {
+			r.routes[i].Name = ""
+		}
+	}
+}
+
+// URL returns the URL path of the named route with the params, which are escaped and checked by the constraints.
+func (r *Router) URL(name string, params map[string]string) (string, error) {
+	pattern, ok := r.routeNames[name]
+	if !ok {
+		return "", ErrRouteNotFound
+	}
+	rp := compileRoute(pattern)
+
+	var b strings.Builder
+	path := rp.path
+	for len(path) > 0 {
+		switch path[0] {
+		case ':', '*':
+			end := len(path)
+			if path[0] == ':' {
+				if i := strings.IndexByte(path, '/'); i >= 0 {
+					end = i
+				}
+			}
+			key := path[1:end]
+			value := params[key]
+			if path[0] == '*' {
+				value = strings.TrimPrefix(value, "/")
+			}
+			if value == "" && path[0] == ':' {
+				return "", &ParamError{Name: key, Err: ErrParamMissing}
+			}
+			if !rp.matchParam(key, value) {
+				return "", &ParamError{Name: key, Value: value, Err: ErrParamConstraint}
+			}
+			if path[0] == '*' {
+				segments := strings.Split(value, "/")
+				for i, segment := range segments {
+					segments[i] = url.PathEscape(segment)
+				}
+				value = strings.Join(segments, "/")
+			} else {
+				value = url.PathEscape(value)
+			}
+			b.WriteString(value)
+			path = path[end:]
+		default:
+			end := strings.IndexAny(path, ":*")
+			if end < 0 {
+				end = len(path)
+			}
+			b.WriteString(path[:end])
+			path = path[end:]
+		}
+	}
+	return b.String(), nil
+}
+
+// routeMethods are the methods in order of the Allow header.
+var routeMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// AllowedMethods returns the methods registered for the request path,
+// HEAD is allowed if GET is registered, and OPTIONS is always allowed if there is any method registered.
+func (r *Router) AllowedMethods(path string) []string {
+	registered := make(map[string]bool)
+	for _, route := range r.routes {
+		if matchPath(route.Path, path) {
+			registered[route.Method] = true
+		}
+	}
+	if len(registered) == 0 {
+		return nil
+	}
+	if registered["GET"] {
+		registered["HEAD"] = true
+	}
+	registered["OPTIONS"] = true
+
+	methods := make([]string, 0, len(registered))
+	for _, method := range routeMethods {
+		if registered[method] {
+			methods = append(methods, method)
+			delete(registered, method)
+		}
+	}
+	// Custom methods.
+	for _, route := range r.routes {
+		if registered[route.Method] {
+			methods = append(methods, route.Method)
+			delete(registered, route.Method)
+		}
+	}
+	return methods
 }
 
-func (r *Router) getHandler(handler Handler) router.Handle {
+// matchPath reports whether the request path matches the route's pattern,
+// such as "/users/:id", "/users/:id<int>" and "/static/*filepath".
+func matchPath(pattern, path string) bool {
+	rp := compileRoute(pattern)
+	pattern = rp.path
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case ':':
+			end := strings.IndexByte(pattern, '/')
+			if end < 0 {
+				end = len(pattern)
+			}
+			value := strings.IndexByte(path, '/')
+			if value < 0 {
+				value = len(path)
+			}
+			if value == 0 || !rp.matchParam(pattern[1:end], path[:value]) {
+				return false
+			}
+			pattern, path = pattern[end:], path[value:]
+		case '*':
+			return rp.matchParam(pattern[1:], path)
+		default:
+			if len(path) == 0 || path[0] != pattern[0] {
+				return false
+			}
+			pattern, path = pattern[1:], path[1:]
+		}
+	}
+	return len(path) == 0
+}
+
+// Handler handles the request.
+//
+// The requests of the methods which are not registered for the request path are answered automatically:
+// HEAD is handled by the GET handler, OPTIONS responses the Allow header, and the others are rejected
+// with status code 405 and the Allow header. The automatic handlers are wrapped by the middlewares.
+func (r *Router) Handler(ctx *fasthttp.RequestCtx) {
+	if r.Router.PanicHandler != nil {
+		defer func() {
+			if rcv := recover(); rcv != nil {
+				r.Router.PanicHandler(ctx, rcv)
+			}
+		}()
+	}
+
+	// The registered routes are scanned only if the tree has no handler for the method,
+	// or the params don't satisfy the constraints, see getHandler.
+	if handle, ps, _ := r.Router.Lookup(string(ctx.Method()), string(ctx.Path()), ctx); handle != nil {
+		handle(ctx, ps)
+		return
+	}
+	if !r.handleMethods(ctx) {
+		r.Router.Handler(ctx)
+	}
+}
+
+// handleMethods answers the request by the other methods registered for the request path,
+// reports whether it is answered.
+func (r *Router) handleMethods(ctx *fasthttp.RequestCtx) bool {
+	method, path := string(ctx.Method()), string(ctx.Path())
+	if len(r.AllowedMethods(path)) == 0 {
+		return false
+	}
+
+	switch {
+	case method == "HEAD" && r.hasRoute("GET", path):
+		// The body of HEAD response is skipped by the server.
+		handle, ps, _ := r.Router.Lookup("GET", path, ctx)
+		if handle == nil {
+			return false
+		}
+		handle(ctx, ps)
+	case method == "OPTIONS":
+		r.getHandler("", HandlerFunc(handleOptions))(ctx, nil)
+	default:
+		r.getHandler("", HandlerFunc(handleMethodNotAllowed))(ctx, nil)
+	}
+	return true
+}
+
+func (r *Router) hasRoute(method, path string) bool {
+	for _, route := range r.routes {
+		if route.Method == method && matchPath(route.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleOptions responses the Allow header with the methods registered for the request path.
+func handleOptions(ctx *Context) {
+	ctx.Response.Header.Set("Allow", strings.Join(ctx.router.AllowedMethods(string(ctx.Path())), ", "))
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// handleMethodNotAllowed rejects the request with status code 405 and the Allow header.
+func handleMethodNotAllowed(ctx *Context) {
+	ctx.HandleError(fasthttp.StatusMethodNotAllowed, ErrMethodNotAllowed)
+	// Set after handling error, which resets the response headers.
+	ctx.Response.Header.Set("Allow", strings.Join(ctx.router.AllowedMethods(string(ctx.Path())), ", "))
+}
+
+// getHandler returns the handler of route wrapped by the middlewares.
+func (r *Router) getHandler(route string, handler Handler) router.Handle {
 	for i := len(r.middlewares) - 1; i >= 0; i-- {
 		handler = r.middlewares[i].Handle(handler)
 	}
 
+	rp := compileRoute(route)
 	return func(_ctx *fasthttp.RequestCtx, ps router.Params) {
+		matched := rp.matchParams(ps)
+		// The requests that don't satisfy the constraints may be answered by the other methods.
+		if !matched && r.handleMethods(_ctx) {
+			return
+		}
+
 		ctx := NewContext(r, _ctx, &ps)
 		defer ctx.Close()
+		ctx.route = route
+		// Reject the requests that don't satisfy the constraints before handling.
+		if !matched {
+			ctx.HandleError(fasthttp.StatusNotFound, ErrNotFound)
+			return
+		}
 		handler.Handle(ctx)
 	}
 }
 
 // RegisterController for registering controller.
 //
-// The Controller should implemented the ControllerInterface.
+// Only the methods which the controller implements are registered, see GETHandler, POSTHandler etc.
+// The request handlers are wrapped by the controller's Handle once, and then the router's middlewares.
 func (r *Router) RegisterController(route string, c ControllerInterface) {
-	handlers := make(map[string]Handler, 0)
-
-	// Register GET request's handler.
-	var getHandler Handler
-	getHandler = c.Handle(HandlerFunc(c.GET))
-	handlers["GET"] = getHandler
-
-	// Register POST request's handler.
-	var postHandler Handler
-	postHandler = c.Handle(HandlerFunc(c.POST))
-	handlers["POST"] = postHandler
-
-	// Register DELETE request's handler.
-	var deleteHandler Handler
-	deleteHandler = c.Handle(HandlerFunc(c.DELETE))
-	handlers["DELETE"] = deleteHandler
-
-	// Register PUT request's handler.
-	var putHandler Handler
-	putHandler = c.Handle(HandlerFunc(c.PUT))
-	handlers["PUT"] = putHandler
-
-	// Register OPTIONS request's handler.
-	var optionsHandler Handler
-	optionsHandler = c.Handle(HandlerFunc(c.OPTIONS))
-	handlers["OPTIONS"] = optionsHandler
-
-	// Register PATCH request's handler.
-	var patchHandler Handler
-	patchHandler = c.Handle(HandlerFunc(c.PATCH))
-	handlers["PATCH"] = patchHandler
-
-	// Register HEAD request's handler.
-	var headHandler Handler
-	headHandler = c.Handle(HandlerFunc(c.HEAD))
-	handlers["HEAD"] = headHandler
-
-	for method, handler := range handlers {
-		var _handler Handler
-		_handler = c.initMiddlewares(c.Handle(handler))
-		// Register middlewares.
-		for i := len(r.middlewares) - 1; i >= 0; i-- {
-			_handler = r.middlewares[i].Handle(_handler)
+	var requirements map[string]Requirement
+	if cr, ok := c.(ControllerRequirements); ok {
+		requirements = cr.Requirements()
+	}
+
+	methods, handlers := controllerHandlers(c)
+	for i, method := range methods {
+		handler := handlers[i]
+		if requirement, ok := requirements[method]; ok {
+			handler = NewAuthorizeMiddleware(requirement).Handle(handler)
 		}
-		// Add to route.
-		r.Router.Handle(method, route, func(_ctx *fasthttp.RequestCtx, ps router.Params) {
-			ctx := NewContext(r, _ctx, &ps)
-			defer ctx.Close()
-			_handler.Handle(ctx)
-		})
+		r.Handle(method, route, c.Handle(handler))
 	}
 }
@@ -0,0 +1,186 @@
+package clevergo
+
+import (
+	"encoding/base64"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// RedirectToWWW redirects the requests of non-www host to www host.
+	RedirectToWWW = "www"
+	// RedirectToNonWWW redirects the requests of www host to non-www host.
+	RedirectToNonWWW = "non-www"
+)
+
+// cspNoncePlaceholder is the placeholder of nonce in ContentSecurityPolicy.
+const cspNoncePlaceholder = "{nonce}"
+
+// SecureConfig for SecureMiddleware, the empty values disable the corresponding headers.
+type SecureConfig struct {
+	// Max age of Strict-Transport-Security in seconds, it is sent over HTTPS only.
+	HSTSMaxAge int
+	// Whether to add includeSubDomains directive to Strict-Transport-Security.
+	HSTSIncludeSubdomains bool
+	// Whether to add preload directive to Strict-Transport-Security.
+	HSTSPreload bool
+	// Content-Security-Policy, the "{nonce}" placeholder is replaced by the per-request nonce,
+	// such as "script-src 'self' 'nonce-{nonce}'".
+	ContentSecurityPolicy string
+	// Whether to send Content-Security-Policy-Report-Only instead.
+	CSPReportOnly bool
+	// X-Frame-Options, such as "DENY" and "SAMEORIGIN".
+	FrameOptions string
+	// Whether to send X-Content-Type-Options: nosniff.
+	ContentTypeNosniff bool
+	// Referrer-Policy.
+	ReferrerPolicy string
+	// Permissions-Policy, such as "geolocation=(), camera=()".
+	PermissionsPolicy string
+	// Cross-Origin-Opener-Policy, such as "same-origin".
+	CrossOriginOpenerPolicy string
+	// Cross-Origin-Embedder-Policy, such as "require-corp".
+	CrossOriginEmbedderPolicy string
+	// Whether to redirect the HTTP requests to HTTPS.
+	HTTPSRedirect bool
+	// RedirectToWWW or RedirectToNonWWW, empty means no redirection.
+	HostRedirect string
+}
+
+// NewSecureConfig returns default secure headers configuration.
+//
+// HSTS and HTTPS redirection are disabled by default, since they affect the browsers
+// for a long time once they are sent.
+func NewSecureConfig() *SecureConfig {
+	return &SecureConfig{
+		FrameOptions:            "SAMEORIGIN",
+		ContentTypeNosniff:      true,
+		ReferrerPolicy:          "strict-origin-when-cross-origin",
+		CrossOriginOpenerPolicy: "same-origin",
+	}
+}
+
+// SecureMiddleware sets the security headers, and redirects the requests to
+// the canonical scheme and host.
+//
+// The nonce of Content-Security-Policy is available by Context.CSPNonce,
+// and by the "cspNonce" template function in Context.Render.
+type SecureMiddleware struct {
+	config *SecureConfig
+	hsts   string
+}
+
+// NewSecureMiddleware returns a SecureMiddleware's instance.
+//
+// The default configuration will be used if config is nil.
+func NewSecureMiddleware(config *SecureConfig) *SecureMiddleware {
+	if config == nil {
+		config = NewSecureConfig()
+	}
+
+	m := &SecureMiddleware{config: config}
+	if config.HSTSMaxAge > 0 {
+		m.hsts = "max-age=" + strconv.Itoa(config.HSTSMaxAge)
+		if config.HSTSIncludeSubdomains {
+			m.hsts += "; includeSubDomains"
+		}
+		if config.HSTSPreload {
+			m.hsts += "; preload"
+		}
+	}
+	return m
+}
+
+// Handle implemented Middleware Interface.
+func (m *SecureMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		if m.redirect(ctx) {
+			return
+		}
+
+		csp := m.config.ContentSecurityPolicy
+		if strings.Contains(csp, cspNoncePlaceholder) {
+			// The URL-safe alphabet is allowed by CSP, and it needs no escaping in templates.
+			nonce := base64.RawURLEncoding.EncodeToString(generateToken(16))
+			ctx.cspNonce = nonce
+			ctx.SetTemplateFunc("cspNonce", func() string {
+				return nonce
+			})
+			csp = strings.Replace(csp, cspNoncePlaceholder, nonce, -1)
+		}
+
+		next.Handle(ctx)
+
+		m.setHeaders(ctx, csp)
+	})
+}
+
+// redirect redirects the request to the canonical scheme and host, reports whether it is redirected.
+func (m *SecureMiddleware) redirect(ctx *Context) bool {
+	scheme, host := ctx.Scheme(), ctx.RealHost()
+	if m.config.HTTPSRedirect {
+		scheme = "https"
+	}
+	isWWW := strings.HasPrefix(strings.ToLower(host), "www.")
+	switch {
+	case m.config.HostRedirect == RedirectToWWW && !isWWW:
+		host = "www." + host
+	case m.config.HostRedirect == RedirectToNonWWW && isWWW:
+		host = host[4:]
+	}
+
+	if scheme == ctx.Scheme() && host == ctx.RealHost() {
+		return false
+	}
+
+	code := fasthttp.StatusMovedPermanently
+	if !ctx.IsGet() && !ctx.IsHead() {
+		// Preserve the method and body.
+		code = fasthttp.StatusPermanentRedirect
+	}
+	ctx.Response.Header.Set("Location", scheme+"://"+host+string(ctx.RequestURI()))
+	ctx.SetStatusCode(code)
+	return true
+}
+
+// setHeaders sets the security headers.
+func (m *SecureMiddleware) setHeaders(ctx *Context, csp string) {
+	header := &ctx.Response.Header
+	if m.hsts != "" && ctx.Scheme() == "https" {
+		header.Set("Strict-Transport-Security", m.hsts)
+	}
+	if csp != "" {
+		if m.config.CSPReportOnly {
+			header.Set("Content-Security-Policy-Report-Only", csp)
+		} else {
+			header.Set("Content-Security-Policy", csp)
+		}
+	}
+	if m.config.FrameOptions != "" {
+		header.Set("X-Frame-Options", m.config.FrameOptions)
+	}
+	if m.config.ContentTypeNosniff {
+		header.Set("X-Content-Type-Options", "nosniff")
+	}
+	if m.config.ReferrerPolicy != "" {
+		header.Set("Referrer-Policy", m.config.ReferrerPolicy)
+	}
+	if m.config.PermissionsPolicy != "" {
+		header.Set("Permissions-Policy", m.config.PermissionsPolicy)
+	}
+	if m.config.CrossOriginOpenerPolicy != "" {
+		header.Set("Cross-Origin-Opener-Policy", m.config.CrossOriginOpenerPolicy)
+	}
+	if m.config.CrossOriginEmbedderPolicy != "" {
+		header.Set("Cross-Origin-Embedder-Policy", m.config.CrossOriginEmbedderPolicy)
+	}
+}
+
+// CSPNonce returns the nonce of Content-Security-Policy of the current request.
+//
+// Returns an empty string if the SecureMiddleware is not applied, or the policy has no nonce.
+func (ctx *Context) CSPNonce() string {
+	ctx.checkReleased()
+	return ctx.cspNonce
+}
@@ -0,0 +1,117 @@
+package clevergo
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestSecureMiddleware(t *testing.T) {
+	config := NewSecureConfig()
+	config.HSTSMaxAge = 31536000
+	config.HSTSIncludeSubdomains = true
+	config.HSTSPreload = true
+	config.ContentSecurityPolicy = "script-src 'self' 'nonce-{nonce}'"
+	config.PermissionsPolicy = "camera=()"
+	config.CrossOriginEmbedderPolicy = "require-corp"
+
+	tpl := template.Must(template.New("page").Funcs(TemplateFuncs()).Parse(`<script nonce="{{ cspNonce }}"></script>`))
+	r := NewRouter()
+	r.AddMiddleware(NewSecureMiddleware(config))
+	r.GET("/", HandlerFunc(func(ctx *Context) {
+		ctx.Render(tpl, nil)
+	}))
+	r.GET("/error", HandlerFunc(func(ctx *Context) {
+		ctx.HandleError(500, nil)
+	}))
+
+	resp := serve(t, r.Handler, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	csp := string(resp.Header.Peek("Content-Security-Policy"))
+	if !strings.HasPrefix(csp, "script-src 'self' 'nonce-") {
+		t.Fatalf("unexpected Content-Security-Policy %q", csp)
+	}
+	nonce := strings.TrimSuffix(strings.TrimPrefix(csp, "script-src 'self' 'nonce-"), "'")
+	if body := string(resp.Body()); body != `<script nonce="`+nonce+`"></script>` {
+		t.Errorf("unexpected body %q, nonce %q", body, nonce)
+	}
+	expected := map[string]string{
+		"X-Frame-Options":              "SAMEORIGIN",
+		"X-Content-Type-Options":       "nosniff",
+		"Referrer-Policy":              "strict-origin-when-cross-origin",
+		"Permissions-Policy":           "camera=()",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Embedder-Policy": "require-corp",
+		// HSTS is sent over HTTPS only.
+		"Strict-Transport-Security": "",
+	}
+	for name, value := range expected {
+		if v := string(resp.Header.Peek(name)); v != value {
+			t.Errorf("unexpected %s %q. Expected %q", name, v, value)
+		}
+	}
+
+	// The nonce is different on each request.
+	resp = serve(t, r.Handler, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	if string(resp.Header.Peek("Content-Security-Policy")) == csp {
+		t.Error("expected a new nonce")
+	}
+
+	// The headers are set on error responses.
+	resp = serve(t, r.Handler, "GET /error HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	if resp.StatusCode() != 500 || string(resp.Header.Peek("X-Frame-Options")) != "SAMEORIGIN" {
+		t.Errorf("unexpected response %d, %q", resp.StatusCode(), resp.Header.Peek("X-Frame-Options"))
+	}
+}
+
+func TestSecureMiddleware_HSTS(t *testing.T) {
+	config := NewSecureConfig()
+	config.HSTSMaxAge = 31536000
+	config.HSTSPreload = true
+	proxies, _ := NewTrustedProxies("0.0.0.0")
+	r := NewRouter()
+	r.SetTrustedProxies(proxies)
+	r.AddMiddleware(NewSecureMiddleware(config))
+	r.GET("/", HandlerFunc(func(ctx *Context) {}))
+
+	resp := serve(t, r.Handler, "GET / HTTP/1.1\r\nHost: example.com\r\nX-Forwarded-Proto: https\r\n\r\n")
+	if v := string(resp.Header.Peek("Strict-Transport-Security")); v != "max-age=31536000; preload" {
+		t.Errorf("unexpected Strict-Transport-Security %q", v)
+	}
+}
+
+func TestSecureMiddleware_Redirect(t *testing.T) {
+	tests := []struct {
+		https    bool
+		host     string
+		request  string
+		code     int
+		location string
+	}{
+		{true, "", "GET /foo?bar=1 HTTP/1.1\r\nHost: example.com\r\n\r\n", 301, "https://example.com/foo?bar=1"},
+		{true, "", "POST /foo HTTP/1.1\r\nHost: example.com\r\n\r\n", 308, "https://example.com/foo"},
+		{true, "", "GET /foo HTTP/1.1\r\nHost: example.com\r\nX-Forwarded-Proto: https\r\n\r\n", 200, ""},
+		{false, RedirectToWWW, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n", 301, "http://www.example.com/"},
+		{false, RedirectToWWW, "GET / HTTP/1.1\r\nHost: www.example.com\r\n\r\n", 200, ""},
+		{true, RedirectToNonWWW, "GET / HTTP/1.1\r\nHost: www.example.com:8443\r\n\r\n", 301, "https://example.com:8443/"},
+		{false, RedirectToNonWWW, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n", 200, ""},
+	}
+	proxies, _ := NewTrustedProxies("0.0.0.0")
+	for _, test := range tests {
+		config := NewSecureConfig()
+		config.HTTPSRedirect = test.https
+		config.HostRedirect = test.host
+		r := NewRouter()
+		r.SetTrustedProxies(proxies)
+		r.AddMiddleware(NewSecureMiddleware(config))
+		r.Handle("GET", "/*path", HandlerFunc(func(ctx *Context) {}))
+		r.Handle("POST", "/*path", HandlerFunc(func(ctx *Context) {}))
+
+		resp := serve(t, r.Handler, test.request)
+		if resp.StatusCode() != test.code {
+			t.Errorf("%q: unexpected status code %d. Expected %d", test.request, resp.StatusCode(), test.code)
+		}
+		if location := string(resp.Header.Peek("Location")); location != test.location {
+			t.Errorf("%q: unexpected location %q. Expected %q", test.request, location, test.location)
+		}
+	}
+}
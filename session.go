@@ -0,0 +1,236 @@
+package clevergo
+
+import (
+	"errors"
+	"github.com/clevergo/sessions"
+	"reflect"
+)
+
+// DefaultSessionName is the default name of session.
+const DefaultSessionName = "CLEVERGO_SESSION"
+
+// ErrNoSessionStore means that there is no session store for the router.
+var ErrNoSessionStore = errors.New("no session store")
+
+// SessionConfig for SessionMiddleware.
+type SessionConfig struct {
+	Name string // Name of session.
+}
+
+// NewSessionConfig returns default session configuration.
+func NewSessionConfig() *SessionConfig {
+	return &SessionConfig{
+		Name: DefaultSessionName,
+	}
+}
+
+// SessionMiddleware manages the session in the request lifecycle.
+//
+// The session is loaded from the router's session store on the first access by Context.GetSession,
+// and it is saved after handling only if it was modified.
+type SessionMiddleware struct {
+	config *SessionConfig
+}
+
+// NewSessionMiddleware returns a SessionMiddleware's instance.
+//
+// The default configuration will be used if config is nil.
+func NewSessionMiddleware(config *SessionConfig) *SessionMiddleware {
+	if config == nil {
+		config = NewSessionConfig()
+	}
+	return &SessionMiddleware{config: config}
+}
+
+// Handle implemented Middleware Interface.
+func (m *SessionMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		ctx.sessionName = m.config.Name
+
+		next.Handle(ctx)
+
+		if err := ctx.SaveSession(); err != nil {
+			ctx.Logger().Printf("Session: failed to save session: %s", err)
+		}
+	})
+}
+
+// GetSession returns the session of current request.
+//
+// The session will be loaded from the router's session store on the first access,
+// and the subsequent calls return the same session.
+func (ctx *Context) GetSession() (*sessions.Session, error) {
+	ctx.checkReleased()
+	if ctx.Session != nil {
+		return ctx.Session, nil
+	}
+
+	store := ctx.SessionStore()
+	if store == nil {
+		return nil, ErrNoSessionStore
+	}
+	session, err := store.Get(ctx.RequestCtx, ctx.getSessionName())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.Session = session
+	ctx.sessionSnapshot = copySessionValues(session.Values)
+	return session, nil
+}
+
+func (ctx *Context) getSessionName() string {
+	if ctx.sessionName != "" {
+		return ctx.sessionName
+	}
+	return DefaultSessionName
+}
+
+// MarkSessionModified marks the session as modified, so that it will be saved.
+//
+// The adding, removing and replacing of session values are detected automatically,
+// it is only required if the session values were modified in place, such as the fields of pointer.
+func (ctx *Context) MarkSessionModified() {
+	ctx.checkReleased()
+	ctx.sessionModified = true
+}
+
+// SaveSession saves the session if it has been loaded and modified.
+//
+// It is invoked by SessionMiddleware after handling,
+// so that there is no need to call it manually.
+func (ctx *Context) SaveSession() error {
+	ctx.checkReleased()
+	if ctx.Session == nil || !ctx.isSessionModified() {
+		return nil
+	}
+
+	if err := ctx.Session.Store().Save(ctx.RequestCtx, ctx.Session); err != nil {
+		return err
+	}
+	ctx.sessionSnapshot = copySessionValues(ctx.Session.Values)
+	ctx.sessionModified = false
+	return nil
+}
+
+// isSessionModified reports whether the session was modified since it was loaded or saved.
+func (ctx *Context) isSessionModified() bool {
+	if ctx.sessionModified {
+		return true
+	}
+	if ctx.sessionSnapshot == nil {
+		// The session was not loaded by GetSession.
+		return false
+	}
+
+	if len(ctx.sessionSnapshot) != len(ctx.Session.Values) {
+		return true
+	}
+	for k, v := range ctx.Session.Values {
+		if old, ok := ctx.sessionSnapshot[k]; !ok || !reflect.DeepEqual(old, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegenerateSession replaces the session with a new one which has the same values,
+// and destroys the old one. It should be called after login to prevent session fixation.
+func (ctx *Context) RegenerateSession() (*sessions.Session, error) {
+	ctx.checkReleased()
+	old, err := ctx.GetSession()
+	if err != nil {
+		return nil, err
+	}
+
+	store := old.Store()
+	session := sessions.NewSession(store, old.Name())
+	for k, v := range old.Values {
+		session.Values[k] = v
+	}
+	if old.Options != nil {
+		options := *old.Options
+		session.Options = &options
+	}
+
+	if !old.IsNew {
+		if err = ctx.destroySession(old); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx.Session = session
+	ctx.sessionSnapshot = copySessionValues(session.Values)
+	ctx.sessionModified = true
+	return session, nil
+}
+
+// DestroySession removes the session values and expires the session.
+func (ctx *Context) DestroySession() error {
+	ctx.checkReleased()
+	session, err := ctx.GetSession()
+	if err != nil {
+		return err
+	}
+
+	for k := range session.Values {
+		delete(session.Values, k)
+	}
+	if err = ctx.destroySession(session); err != nil {
+		return err
+	}
+
+	ctx.sessionSnapshot = copySessionValues(session.Values)
+	ctx.sessionModified = false
+	return nil
+}
+
+// destroySession expires the session by saving it with negative MaxAge.
+func (ctx *Context) destroySession(session *sessions.Session) error {
+	options := sessions.Options{}
+	if session.Options != nil {
+		options = *session.Options
+	}
+	options.MaxAge = -1
+	session.Options = &options
+	return session.Store().Save(ctx.RequestCtx, session)
+}
+
+// AddFlash adds a flash message to the session.
+//
+// A single variadic argument is accepted, and it is optional: it defines the flash key.
+func (ctx *Context) AddFlash(value interface{}, vars ...string) error {
+	ctx.checkReleased()
+	session, err := ctx.GetSession()
+	if err != nil {
+		return err
+	}
+	session.AddFlash(value, vars...)
+	ctx.MarkSessionModified()
+	return nil
+}
+
+// Flashes returns and removes the flash messages from the session.
+//
+// A single variadic argument is accepted, and it is optional: it defines the flash key.
+func (ctx *Context) Flashes(vars ...string) ([]interface{}, error) {
+	ctx.checkReleased()
+	session, err := ctx.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	flashes := session.Flashes(vars...)
+	if len(flashes) > 0 {
+		ctx.MarkSessionModified()
+	}
+	return flashes, nil
+}
+
+// copySessionValues returns a shallow copy of session values.
+func copySessionValues(values map[interface{}]interface{}) map[interface{}]interface{} {
+	snapshot := make(map[interface{}]interface{}, len(values))
+	for k, v := range values {
+		snapshot[k] = v
+	}
+	return snapshot
+}
@@ -0,0 +1,102 @@
+package clevergo
+
+import (
+	"testing"
+)
+
+func TestSessionMiddleware(t *testing.T) {
+	store := newTestSessionStore()
+	r := NewRouter()
+	r.SetSessionStore(store)
+	r.AddMiddleware(NewSessionMiddleware(&SessionConfig{Name: "sess"}))
+
+	r.GET("/read", HandlerFunc(func(ctx *Context) {
+		session, err := ctx.GetSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx.Textf("%v", session.Values["user"])
+	}))
+	r.GET("/none", HandlerFunc(func(ctx *Context) {
+		ctx.Text("none")
+	}))
+	r.GET("/login", HandlerFunc(func(ctx *Context) {
+		if _, err := ctx.RegenerateSession(); err != nil {
+			t.Fatal(err)
+		}
+		ctx.Session.Values["user"] = "foo"
+		ctx.Error("reset", 500)
+	}))
+	r.GET("/flash", HandlerFunc(func(ctx *Context) {
+		if err := ctx.AddFlash("saved"); err != nil {
+			t.Fatal(err)
+		}
+	}))
+	r.GET("/flashes", HandlerFunc(func(ctx *Context) {
+		flashes, err := ctx.Flashes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx.Textf("%v", flashes)
+	}))
+	r.GET("/logout", HandlerFunc(func(ctx *Context) {
+		if err := ctx.DestroySession(); err != nil {
+			t.Fatal(err)
+		}
+	}))
+
+	serve(t, r.Handler, "GET /none HTTP/1.1\r\n\r\n")
+	serve(t, r.Handler, "GET /read HTTP/1.1\r\n\r\n")
+	if store.saved != 0 {
+		t.Errorf("Unmodified session should not be saved, saved %d times", store.saved)
+	}
+
+	serve(t, r.Handler, "GET /login HTTP/1.1\r\n\r\n")
+	if store.saved != 1 {
+		t.Errorf("Modified session should be saved once, saved %d times", store.saved)
+	}
+	session := store.sessions["sess"]
+	if session == nil || session.Values["user"] != "foo" {
+		t.Fatalf("Unexpected session %v", session)
+	}
+
+	session.IsNew = false
+	serve(t, r.Handler, "GET /login HTTP/1.1\r\n\r\n")
+	if store.saved != 3 {
+		t.Errorf("The old session should be destroyed when regenerating, saved %d times", store.saved)
+	}
+	if session.Options.MaxAge != -1 {
+		t.Errorf("The old session should be expired")
+	}
+	if store.sessions["sess"] == session {
+		t.Errorf("The session should be regenerated")
+	}
+
+	serve(t, r.Handler, "GET /flash HTTP/1.1\r\n\r\n")
+	resp := serve(t, r.Handler, "GET /flashes HTTP/1.1\r\n\r\n")
+	if string(resp.Body()) != "[saved]" {
+		t.Errorf("Unexpected flashes %q", resp.Body())
+	}
+	resp = serve(t, r.Handler, "GET /flashes HTTP/1.1\r\n\r\n")
+	if string(resp.Body()) != "[]" {
+		t.Errorf("Flashes should be removed after reading, got %q", resp.Body())
+	}
+
+	serve(t, r.Handler, "GET /logout HTTP/1.1\r\n\r\n")
+	if session := store.sessions["sess"]; len(session.Values) != 0 || session.Options.MaxAge != -1 {
+		t.Errorf("The session should be destroyed")
+	}
+}
+
+func TestContext_GetSession(t *testing.T) {
+	r := NewRouter()
+	r.GET("/", HandlerFunc(func(ctx *Context) {
+		if _, err := ctx.GetSession(); err != ErrNoSessionStore {
+			t.Errorf("Unexpected error %v. Expected %v", err, ErrNoSessionStore)
+		}
+		if err := ctx.SaveSession(); err != nil {
+			t.Errorf("Unexpected error %v", err)
+		}
+	}))
+	serve(t, r.Handler, "GET / HTTP/1.1\r\n\r\n")
+}
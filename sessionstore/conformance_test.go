@@ -0,0 +1,158 @@
+package sessionstore
+
+import (
+	"github.com/clevergo/sessions"
+	"github.com/valyala/fasthttp"
+	"testing"
+	"time"
+)
+
+const testSessionName = "sess"
+
+// request creates a request context with the cookie of the previous response.
+func request(previous *fasthttp.RequestCtx) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	if previous != nil {
+		if value := previous.Response.Header.PeekCookie(testSessionName); len(value) > 0 {
+			cookie := fasthttp.AcquireCookie()
+			defer fasthttp.ReleaseCookie(cookie)
+			cookie.ParseBytes(value)
+			ctx.Request.Header.SetCookieBytesKV([]byte(testSessionName), cookie.Value())
+		}
+	}
+	return ctx
+}
+
+// responseCookie returns the session cookie of response.
+func responseCookie(t *testing.T, ctx *fasthttp.RequestCtx) *fasthttp.Cookie {
+	cookie := &fasthttp.Cookie{}
+	cookie.SetKey(testSessionName)
+	if !ctx.Response.Header.Cookie(cookie) {
+		t.Fatalf("Expected session cookie")
+	}
+	return cookie
+}
+
+// setTime replaces timeNow with the fixed time, and returns the function to restore it.
+func setTime(now time.Time) func() {
+	timeNow = func() time.Time {
+		return now
+	}
+	return func() {
+		timeNow = time.Now
+	}
+}
+
+// testStoreConformance is the conformance test suite which every store must pass.
+func testStoreConformance(t *testing.T, store sessions.Store) {
+	t.Run("New", func(t *testing.T) {
+		session, err := store.Get(request(nil), testSessionName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !session.IsNew || len(session.Values) != 0 || session.Name() != testSessionName || session.Store() != store {
+			t.Errorf("Unexpected new session %+v", session)
+		}
+	})
+
+	t.Run("SaveAndLoad", func(t *testing.T) {
+		ctx := request(nil)
+		session, _ := store.Get(ctx, testSessionName)
+		session.Values["user"] = "foo"
+		session.Values[42] = 3.14
+		session.AddFlash("hello")
+		if err := store.Save(ctx, session); err != nil {
+			t.Fatal(err)
+		}
+		cookie := responseCookie(t, ctx)
+		if !cookie.HTTPOnly() || string(cookie.Path()) != "/" {
+			t.Errorf("Unexpected cookie %s", cookie)
+		}
+
+		next := request(ctx)
+		loaded, err := store.Get(next, testSessionName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loaded.IsNew {
+			t.Errorf("Loaded session should not be new")
+		}
+		if loaded.Values["user"] != "foo" || loaded.Values[42] != 3.14 {
+			t.Errorf("Unexpected values %v", loaded.Values)
+		}
+		if flashes := loaded.Flashes(); len(flashes) != 1 || flashes[0] != "hello" {
+			t.Errorf("Unexpected flashes %v", flashes)
+		}
+
+		// Modifying the loaded session doesn't affect the stored one until saving.
+		loaded.Values["user"] = "bar"
+		if again, _ := store.Get(request(ctx), testSessionName); again.Values["user"] != "foo" {
+			t.Errorf("Unexpected values %v", again.Values)
+		}
+		if err = store.Save(next, loaded); err != nil {
+			t.Fatal(err)
+		}
+		if again, _ := store.Get(request(next), testSessionName); again.Values["user"] != "bar" {
+			t.Errorf("Unexpected values %v", again.Values)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		ctx := request(nil)
+		ctx.Request.Header.SetCookie(testSessionName, "tampered")
+		session, _ := store.Get(ctx, testSessionName)
+		if session == nil || !session.IsNew || len(session.Values) != 0 {
+			t.Errorf("Tampered cookie should result in a new session, got %+v", session)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		ctx := request(nil)
+		session, _ := store.Get(ctx, testSessionName)
+		session.Values["user"] = "foo"
+		if err := store.Save(ctx, session); err != nil {
+			t.Fatal(err)
+		}
+
+		next := request(ctx)
+		session, _ = store.Get(next, testSessionName)
+		session.Options.MaxAge = -1
+		if err := store.Save(next, session); err != nil {
+			t.Fatal(err)
+		}
+		if cookie := responseCookie(t, next); len(cookie.Value()) != 0 || !cookie.Expire().Before(time.Now()) {
+			t.Errorf("Cookie should be expired: %s", cookie)
+		}
+
+		// The server side data should be removed even if the client still sends the old cookie.
+		if session, _ = store.Get(request(ctx), testSessionName); !session.IsNew {
+			if _, ok := store.(*CookieStore); !ok {
+				t.Errorf("Deleted session should not be loaded")
+			}
+		}
+	})
+
+	t.Run("Expire", func(t *testing.T) {
+		ctx := request(nil)
+		session, _ := store.Get(ctx, testSessionName)
+		session.Options.MaxAge = 60
+		session.Values["user"] = "foo"
+		if err := store.Save(ctx, session); err != nil {
+			t.Fatal(err)
+		}
+
+		restore := setTime(time.Now().Add(30 * time.Second))
+		session, _ = store.Get(request(ctx), testSessionName)
+		restore()
+		if session.IsNew {
+			t.Errorf("Session should not expire before max age")
+		}
+
+		restore = setTime(time.Now().Add(2 * time.Minute))
+		defer restore()
+		session, _ = store.Get(request(ctx), testSessionName)
+		if !session.IsNew || len(session.Values) != 0 {
+			t.Errorf("Session should expire after max age")
+		}
+	})
+}
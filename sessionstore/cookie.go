@@ -0,0 +1,199 @@
+package sessionstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"github.com/clevergo/sessions"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+var (
+	// ErrInvalidCookie means that the cookie is malformed or its signature doesn't match.
+	ErrInvalidCookie = errors.New("sessionstore: invalid cookie")
+	// ErrCookieExpired means that the cookie has expired.
+	ErrCookieExpired = errors.New("sessionstore: cookie expired")
+	// ErrCookieTooLong means that the encoded cookie exceeds the max length.
+	ErrCookieTooLong = errors.New("sessionstore: cookie too long")
+)
+
+// maxCookieLength is the max length of cookie value that most browsers support.
+const maxCookieLength = 4096
+
+// KeyPair contains the keys for signing and encrypting cookies.
+type KeyPair struct {
+	HashKey  []byte // Key for HMAC-SHA256 signature, 32 or 64 bytes are recommended.
+	BlockKey []byte // Key for AES-GCM encryption, it should be 16, 24 or 32 bytes, nil means no encryption.
+}
+
+// CookieStore keeps the session values in signed and optionally encrypted cookies.
+//
+// The cookies are always encoded by the first key pair, and they are decoded by trying
+// all key pairs in order, so that the keys can be rotated by prepending the new key pair.
+type CookieStore struct {
+	Options  *sessions.Options // Default options of new sessions.
+	keyPairs []KeyPair
+	aeads    []cipher.AEAD
+}
+
+// NewCookieStore returns a CookieStore's instance with default options.
+//
+// It returns an error if there is no key pair, any of the hash keys is empty or any of the block keys is invalid.
+func NewCookieStore(keyPairs ...KeyPair) (*CookieStore, error) {
+	if len(keyPairs) == 0 {
+		return nil, errors.New("sessionstore: no key pair")
+	}
+
+	s := &CookieStore{
+		Options:  NewOptions(),
+		keyPairs: keyPairs,
+		aeads:    make([]cipher.AEAD, len(keyPairs)),
+	}
+	for i, pair := range keyPairs {
+		if len(pair.HashKey) == 0 {
+			return nil, errors.New("sessionstore: hash key is required")
+		}
+		if pair.BlockKey == nil {
+			continue
+		}
+		block, err := aes.NewCipher(pair.BlockKey)
+		if err != nil {
+			return nil, err
+		}
+		if s.aeads[i], err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Get returns a session for the given name.
+func (s *CookieStore) Get(ctx *fasthttp.RequestCtx, name string) (*sessions.Session, error) {
+	return s.New(ctx, name)
+}
+
+// New returns the session decoded from the request's cookie, or a new session if there is none.
+//
+// A new session and an error will be returned if the cookie is invalid.
+func (s *CookieStore) New(ctx *fasthttp.RequestCtx, name string) (*sessions.Session, error) {
+	session := newSession(s, name, s.Options)
+
+	value := ctx.Request.Header.Cookie(name)
+	if len(value) == 0 {
+		return session, nil
+	}
+
+	data, err := s.decode(name, string(value))
+	if err != nil {
+		return session, err
+	}
+	if err = decodeValues(data, session.Values); err != nil {
+		return session, err
+	}
+
+	session.IsNew = false
+	return session, nil
+}
+
+// Save encodes the session values into cookie.
+//
+// The cookie will be expired if the session's MaxAge is negative.
+func (s *CookieStore) Save(ctx *fasthttp.RequestCtx, session *sessions.Session) error {
+	options := sessionOptions(session, s.Options)
+	if options.MaxAge < 0 {
+		setCookie(ctx, session.Name(), "", options)
+		return nil
+	}
+
+	data, err := encodeValues(session.Values)
+	if err != nil {
+		return err
+	}
+	value, err := s.encode(session.Name(), data, expiresAt(options))
+	if err != nil {
+		return err
+	}
+
+	setCookie(ctx, session.Name(), value, options)
+	return nil
+}
+
+// encode signs and encrypts the data by the first key pair.
+//
+// The format is base64(expires | payload | HMAC(name | expires | payload)),
+// the expiration time is in Unix seconds, and the payload is nonce | ciphertext if encryption is enabled.
+func (s *CookieStore) encode(name string, data []byte, expires time.Time) (string, error) {
+	payload := data
+	if aead := s.aeads[0]; aead != nil {
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return "", err
+		}
+		payload = aead.Seal(nonce, nonce, data, []byte(name))
+	}
+
+	b := make([]byte, 8, 8+len(payload)+sha256.Size)
+	binary.BigEndian.PutUint64(b, uint64(expires.Unix()))
+	b = append(b, payload...)
+	b = append(b, signature(s.keyPairs[0].HashKey, name, b)...)
+
+	value := base64.RawURLEncoding.EncodeToString(b)
+	if len(value) > maxCookieLength {
+		return "", ErrCookieTooLong
+	}
+	return value, nil
+}
+
+// decode verifies and decrypts the cookie value by trying all key pairs in order.
+func (s *CookieStore) decode(name, value string) ([]byte, error) {
+	if len(value) > maxCookieLength {
+		return nil, ErrCookieTooLong
+	}
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) < 8+sha256.Size {
+		return nil, ErrInvalidCookie
+	}
+	message, mac := b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+
+	for i, pair := range s.keyPairs {
+		if !hmac.Equal(mac, signature(pair.HashKey, name, message)) {
+			continue
+		}
+
+		expires := time.Unix(int64(binary.BigEndian.Uint64(message)), 0)
+		if !expires.After(timeNow()) {
+			return nil, ErrCookieExpired
+		}
+
+		payload := message[8:]
+		aead := s.aeads[i]
+		if aead == nil {
+			return payload, nil
+		}
+		if len(payload) < aead.NonceSize() {
+			return nil, ErrInvalidCookie
+		}
+		data, err := aead.Open(nil, payload[:aead.NonceSize()], payload[aead.NonceSize():], []byte(name))
+		if err != nil {
+			return nil, ErrInvalidCookie
+		}
+		return data, nil
+	}
+
+	return nil, ErrInvalidCookie
+}
+
+// signature returns the HMAC-SHA256 of the cookie name and message.
+func signature(key []byte, name string, message []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write(message)
+	return h.Sum(nil)
+}
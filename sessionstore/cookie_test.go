@@ -0,0 +1,97 @@
+package sessionstore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+var (
+	testHashKey  = bytes.Repeat([]byte("h"), 32)
+	testBlockKey = bytes.Repeat([]byte("b"), 32)
+)
+
+func TestCookieStore(t *testing.T) {
+	signed, err := NewCookieStore(KeyPair{HashKey: testHashKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("Signed", func(t *testing.T) {
+		testStoreConformance(t, signed)
+	})
+
+	encrypted, err := NewCookieStore(KeyPair{HashKey: testHashKey, BlockKey: testBlockKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("Encrypted", func(t *testing.T) {
+		testStoreConformance(t, encrypted)
+	})
+}
+
+func TestNewCookieStore(t *testing.T) {
+	invalid := [][]KeyPair{
+		nil,
+		{{HashKey: nil}},
+		{{HashKey: testHashKey, BlockKey: []byte("short")}},
+	}
+	for _, keyPairs := range invalid {
+		if _, err := NewCookieStore(keyPairs...); err == nil {
+			t.Errorf("NewCookieStore(%v) should fail", keyPairs)
+		}
+	}
+}
+
+func TestCookieStore_Encrypted(t *testing.T) {
+	store, _ := NewCookieStore(KeyPair{HashKey: testHashKey, BlockKey: testBlockKey})
+	value, err := store.encode(testSessionName, []byte("secret value"), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(value)
+	if bytes.Contains(raw, []byte("secret value")) {
+		t.Errorf("Encrypted cookie should not contain the plain text")
+	}
+
+	// The cookie can not be used under another name.
+	if _, err = store.decode("other", value); err != ErrInvalidCookie {
+		t.Errorf("Unexpected error %v. Expected %v", err, ErrInvalidCookie)
+	}
+}
+
+func TestCookieStore_KeyRotation(t *testing.T) {
+	oldPair := KeyPair{HashKey: []byte("old-hash-key"), BlockKey: bytes.Repeat([]byte("o"), 16)}
+	newPair := KeyPair{HashKey: []byte("new-hash-key"), BlockKey: bytes.Repeat([]byte("n"), 16)}
+
+	oldStore, _ := NewCookieStore(oldPair)
+	rotated, _ := NewCookieStore(newPair, oldPair)
+	newStore, _ := NewCookieStore(newPair)
+
+	ctx := request(nil)
+	session, _ := oldStore.Get(ctx, testSessionName)
+	session.Values["user"] = "foo"
+	if err := oldStore.Save(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+
+	// The rotated store accepts the cookie issued by the old key.
+	next := request(ctx)
+	session, err := rotated.Get(next, testSessionName)
+	if err != nil || session.Values["user"] != "foo" {
+		t.Fatalf("Unexpected session %v: %v", session.Values, err)
+	}
+
+	// And re-issues it by the new key.
+	if err = rotated.Save(next, session); err != nil {
+		t.Fatal(err)
+	}
+	if session, err = newStore.Get(request(next), testSessionName); err != nil || session.Values["user"] != "foo" {
+		t.Errorf("Unexpected session %v: %v", session.Values, err)
+	}
+
+	// The store without old key rejects the old cookie.
+	if _, err = newStore.Get(request(ctx), testSessionName); err != ErrInvalidCookie {
+		t.Errorf("Unexpected error %v. Expected %v", err, ErrInvalidCookie)
+	}
+}
@@ -0,0 +1,129 @@
+package sessionstore
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// sessionFilePrefix is the prefix of session files.
+const sessionFilePrefix = "session_"
+
+// FileSystemStore keeps the sessions in files of a directory.
+type FileSystemStore struct {
+	*ServerStore
+	dir string
+}
+
+// NewFileSystemStore returns a FileSystemStore's instance, the directory will be created if it doesn't exist.
+func NewFileSystemStore(dir string) (*FileSystemStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	s := &FileSystemStore{dir: dir}
+	s.ServerStore = newServerStore(s, s)
+	return s, nil
+}
+
+func (s *FileSystemStore) filename(id string) string {
+	return filepath.Join(s.dir, sessionFilePrefix+id)
+}
+
+// Load implemented Backend Interface.
+//
+// The file contains the expiration time in Unix seconds followed by the session data.
+func (s *FileSystemStore) Load(id string) ([]byte, error) {
+	if !validID(id) {
+		return nil, ErrInvalidID
+	}
+
+	content, err := os.ReadFile(s.filename(id))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	expires, data, ok := decodeFile(content)
+	if !ok || !expires.After(timeNow()) {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+// Persist implemented Backend Interface.
+func (s *FileSystemStore) Persist(id string, data []byte, expires time.Time) error {
+	if !validID(id) {
+		return ErrInvalidID
+	}
+
+	content := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(content, uint64(expires.Unix()))
+	copy(content[8:], data)
+
+	// Write to a temporary file then rename it, so that the readers never see a partial file.
+	f, err := os.CreateTemp(s.dir, ".tmp_"+sessionFilePrefix)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(content); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), s.filename(id))
+}
+
+// Delete implemented Backend Interface.
+func (s *FileSystemStore) Delete(id string) error {
+	if !validID(id) {
+		return ErrInvalidID
+	}
+
+	err := os.Remove(s.filename(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// GC removes the expired session files.
+func (s *FileSystemStore) GC() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	now := timeNow()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), sessionFilePrefix) {
+			continue
+		}
+		name := filepath.Join(s.dir, entry.Name())
+		content, err := os.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		if expires, _, ok := decodeFile(content); !ok || !expires.After(now) {
+			os.Remove(name)
+		}
+	}
+	return nil
+}
+
+// decodeFile returns the expiration time and data of session file's content.
+func decodeFile(content []byte) (time.Time, []byte, bool) {
+	if len(content) < 8 {
+		return time.Time{}, nil, false
+	}
+	expires := time.Unix(int64(binary.BigEndian.Uint64(content)), 0)
+	return expires, content[8:], true
+}
@@ -0,0 +1,48 @@
+package sessionstore
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFileSystemStore(t *testing.T) {
+	store, err := NewFileSystemStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStoreConformance(t, store)
+}
+
+func TestFileSystemStore_GC(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileSystemStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.Persist(newID(), []byte("expired"), time.Now().Add(-time.Second))
+	alive := newID()
+	store.Persist(alive, []byte("alive"), time.Now().Add(time.Hour))
+
+	if err = store.GC(); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != sessionFilePrefix+alive {
+		t.Errorf("Unexpected files after GC: %v", entries)
+	}
+}
+
+func TestFileSystemStore_InvalidID(t *testing.T) {
+	store, err := NewFileSystemStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"", "../../etc/passwd", newID()[:63] + "/"} {
+		if _, err := store.Load(id); err != ErrInvalidID {
+			t.Errorf("Load(%q) = %v, expect %v", id, err, ErrInvalidID)
+		}
+	}
+}
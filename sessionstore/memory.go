@@ -0,0 +1,112 @@
+package sessionstore
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the sessions in memory, the expired sessions are evicted periodically.
+//
+// The sessions will be lost on restart, and they are not shared between processes.
+type MemoryStore struct {
+	*ServerStore
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+	stop    chan struct{}
+	wg      sync.WaitGroup // Running eviction.
+}
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemoryStore returns a MemoryStore's instance,
+// which evicts the expired sessions at every cleanup interval.
+//
+// The eviction is disabled if the cleanup interval is not positive,
+// the expired sessions are still invisible.
+func NewMemoryStore(cleanupInterval time.Duration) *MemoryStore {
+	s := &MemoryStore{
+		entries: make(map[string]memoryEntry),
+		stop:    make(chan struct{}),
+	}
+	s.ServerStore = newServerStore(s, s)
+
+	if cleanupInterval > 0 {
+		s.wg.Add(1)
+		go s.cleanup(cleanupInterval)
+	}
+	return s
+}
+
+// Load implemented Backend Interface.
+func (s *MemoryStore) Load(id string) ([]byte, error) {
+	s.mu.RLock()
+	entry, ok := s.entries[id]
+	s.mu.RUnlock()
+
+	if !ok || !entry.expires.After(timeNow()) {
+		return nil, ErrNotFound
+	}
+	return entry.data, nil
+}
+
+// Persist implemented Backend Interface.
+func (s *MemoryStore) Persist(id string, data []byte, expires time.Time) error {
+	s.mu.Lock()
+	s.entries[id] = memoryEntry{data: data, expires: expires}
+	s.mu.Unlock()
+	return nil
+}
+
+// Delete implemented Backend Interface.
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	delete(s.entries, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// GC evicts the expired sessions.
+func (s *MemoryStore) GC() {
+	now := timeNow()
+	s.mu.Lock()
+	for id, entry := range s.entries {
+		if !entry.expires.After(now) {
+			delete(s.entries, id)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// Len returns the number of sessions, including the expired but not evicted ones.
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// Close stops the periodic eviction, and waits for the running eviction to finish.
+func (s *MemoryStore) Close() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	s.wg.Wait()
+}
+
+func (s *MemoryStore) cleanup(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.GC()
+		case <-s.stop:
+			return
+		}
+	}
+}
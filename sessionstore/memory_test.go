@@ -0,0 +1,28 @@
+package sessionstore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(0)
+	defer store.Close()
+	testStoreConformance(t, store)
+}
+
+func TestMemoryStore_GC(t *testing.T) {
+	store := NewMemoryStore(time.Millisecond)
+	defer store.Close()
+
+	store.Persist(newID(), []byte("expired"), time.Now().Add(-time.Second))
+	store.Persist(newID(), []byte("alive"), time.Now().Add(time.Hour))
+
+	deadline := time.Now().Add(time.Second)
+	for store.Len() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if store.Len() != 1 {
+		t.Errorf("Expired session should be evicted, %d sessions left", store.Len())
+	}
+}
@@ -0,0 +1,113 @@
+package sessionstore
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLDialect is the SQL dialect of database.
+type SQLDialect int
+
+const (
+	// SQLite uses "?" placeholders and the BLOB column, it requires SQLite 3.24 or later.
+	SQLite SQLDialect = iota
+	// MySQL uses "?" placeholders and the MEDIUMBLOB column.
+	MySQL
+	// PostgreSQL uses "$1", "$2" placeholders and the BYTEA column, it requires PostgreSQL 9.5 or later.
+	PostgreSQL
+)
+
+// SQLStore keeps the sessions in a SQL database table.
+//
+// The table contains the columns "id", "data" and "expires_at" (Unix seconds),
+// see also SQLStore.CreateTable.
+type SQLStore struct {
+	*ServerStore
+	db      *sql.DB
+	table   string
+	dialect SQLDialect
+}
+
+// NewSQLStore returns a SQLStore's instance.
+func NewSQLStore(db *sql.DB, table string, dialect SQLDialect) *SQLStore {
+	s := &SQLStore{
+		db:      db,
+		table:   table,
+		dialect: dialect,
+	}
+	s.ServerStore = newServerStore(s, s)
+	return s
+}
+
+// CreateTable creates the sessions table if it doesn't exist.
+func (s *SQLStore) CreateTable() error {
+	blob := "BLOB"
+	switch s.dialect {
+	case MySQL:
+		blob = "MEDIUMBLOB"
+	case PostgreSQL:
+		blob = "BYTEA"
+	}
+	_, err := s.db.Exec(fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (id VARCHAR(64) NOT NULL PRIMARY KEY, data %s NOT NULL, expires_at BIGINT NOT NULL)",
+		s.table, blob,
+	))
+	return err
+}
+
+// bind replaces the "?" in query with the dialect's placeholders.
+func (s *SQLStore) bind(query string) string {
+	if s.dialect != PostgreSQL {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Load implemented Backend Interface.
+func (s *SQLStore) Load(id string) ([]byte, error) {
+	var data []byte
+	query := s.bind(fmt.Sprintf("SELECT data FROM %s WHERE id = ? AND expires_at > ?", s.table))
+	err := s.db.QueryRow(query, id, timeNow().Unix()).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Persist implemented Backend Interface.
+//
+// It inserts or replaces the row by a single statement, so that the concurrent saves of the same session
+// don't conflict on the primary key.
+func (s *SQLStore) Persist(id string, data []byte, expires time.Time) error {
+	query := "INSERT INTO %s (id, data, expires_at) VALUES (?, ?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data, expires_at = excluded.expires_at"
+	if s.dialect == MySQL {
+		query = "INSERT INTO %s (id, data, expires_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE data = VALUES(data), expires_at = VALUES(expires_at)"
+	}
+	_, err := s.db.Exec(s.bind(fmt.Sprintf(query, s.table)), id, data, expires.Unix())
+	return err
+}
+
+// Delete implemented Backend Interface.
+func (s *SQLStore) Delete(id string) error {
+	_, err := s.db.Exec(s.bind(fmt.Sprintf("DELETE FROM %s WHERE id = ?", s.table)), id)
+	return err
+}
+
+// GC removes the expired sessions.
+func (s *SQLStore) GC() error {
+	_, err := s.db.Exec(s.bind(fmt.Sprintf("DELETE FROM %s WHERE expires_at <= ?", s.table)), timeNow().Unix())
+	return err
+}
@@ -0,0 +1,81 @@
+package sessionstore
+
+import (
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newSQLiteStore(t *testing.T) *SQLStore {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	store := NewSQLStore(db, "sessions", SQLite)
+	if err = store.CreateTable(); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestSQLStore(t *testing.T) {
+	testStoreConformance(t, newSQLiteStore(t))
+}
+
+func TestSQLStore_GC(t *testing.T) {
+	store := newSQLiteStore(t)
+	store.Persist(newID(), []byte("expired"), time.Now().Add(-time.Second))
+	store.Persist(newID(), []byte("alive"), time.Now().Add(time.Hour))
+
+	if err := store.GC(); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := store.db.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&n); err != nil || n != 1 {
+		t.Errorf("Unexpected sessions count %d after GC: %v", n, err)
+	}
+}
+
+func TestSQLStore_Bind(t *testing.T) {
+	store := NewSQLStore(nil, "sessions", PostgreSQL)
+	if query := store.bind("SELECT data FROM sessions WHERE id = ? AND expires_at > ?"); query != "SELECT data FROM sessions WHERE id = $1 AND expires_at > $2" {
+		t.Errorf("Unexpected query %q", query)
+	}
+}
+
+func TestSQLStore_ConcurrentPersist(t *testing.T) {
+	store := newSQLiteStore(t)
+	store.db.SetMaxOpenConns(1)
+	id := newID()
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- store.Persist(id, []byte{byte(i)}, time.Now().Add(time.Hour))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := store.Load(id); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	var n int
+	if err := store.db.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&n); err != nil || n != 1 {
+		t.Errorf("Unexpected sessions count %d: %v", n, err)
+	}
+}
@@ -0,0 +1,232 @@
+// Package sessionstore provides the built-in session stores for CleverGo,
+// all of them implement the sessions.Store interface.
+//
+// The CookieStore keeps the session values in signed and optionally encrypted cookies,
+// and the server side stores (MemoryStore, FileSystemStore and SQLStore) keep only
+// the random session ID in cookie.
+package sessionstore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"github.com/clevergo/sessions"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+var (
+	// ErrNotFound means that the session data doesn't exist or has expired.
+	ErrNotFound = errors.New("sessionstore: session not found")
+	// ErrInvalidID means that the session ID is malformed.
+	ErrInvalidID = errors.New("sessionstore: invalid session ID")
+)
+
+// timeNow returns the current time, it is replaceable for testing.
+var timeNow = time.Now
+
+// defaultMaxAge is the default max age of session in seconds.
+const defaultMaxAge = 86400 * 30
+
+func init() {
+	// Flash messages.
+	gob.Register([]interface{}{})
+}
+
+// NewOptions returns default session options.
+func NewOptions() *sessions.Options {
+	return &sessions.Options{
+		Path:     "/",
+		MaxAge:   defaultMaxAge,
+		HttpOnly: true,
+	}
+}
+
+// Backend persists the encoded session data by session ID.
+type Backend interface {
+	// Load returns the data of session, returns ErrNotFound if it doesn't exist or has expired.
+	Load(id string) ([]byte, error)
+	// Persist saves the data of session until it expires.
+	Persist(id string, data []byte, expires time.Time) error
+	// Delete removes the session.
+	Delete(id string) error
+}
+
+// ServerStore is a session store which keeps the session values in backend,
+// and keeps only the session ID in cookie.
+type ServerStore struct {
+	Options *sessions.Options // Default options of new sessions.
+	backend Backend
+	owner   sessions.Store // The store which the sessions belong to.
+}
+
+// NewServerStore returns a ServerStore's instance with default options.
+func NewServerStore(backend Backend) *ServerStore {
+	s := newServerStore(nil, backend)
+	s.owner = s
+	return s
+}
+
+// newServerStore returns a ServerStore's instance which is embedded by owner.
+func newServerStore(owner sessions.Store, backend Backend) *ServerStore {
+	return &ServerStore{
+		Options: NewOptions(),
+		backend: backend,
+		owner:   owner,
+	}
+}
+
+// Get returns a session for the given name.
+func (s *ServerStore) Get(ctx *fasthttp.RequestCtx, name string) (*sessions.Session, error) {
+	return s.New(ctx, name)
+}
+
+// New returns the existing session of the request, or a new session if there is none.
+//
+// A new session and an error will be returned if the existing session is invalid.
+func (s *ServerStore) New(ctx *fasthttp.RequestCtx, name string) (*sessions.Session, error) {
+	session := newSession(s.owner, name, s.Options)
+
+	id := string(ctx.Request.Header.Cookie(name))
+	if id == "" {
+		return session, nil
+	}
+	if !validID(id) {
+		return session, ErrInvalidID
+	}
+
+	data, err := s.backend.Load(id)
+	if err == ErrNotFound {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+	if err = decodeValues(data, session.Values); err != nil {
+		return session, err
+	}
+
+	session.ID = id
+	session.IsNew = false
+	return session, nil
+}
+
+// Save persists the session, and sets the session ID cookie.
+//
+// The session will be deleted if its MaxAge is negative.
+func (s *ServerStore) Save(ctx *fasthttp.RequestCtx, session *sessions.Session) error {
+	options := sessionOptions(session, s.Options)
+
+	if options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.backend.Delete(session.ID); err != nil {
+				return err
+			}
+		}
+		setCookie(ctx, session.Name(), "", options)
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = newID()
+	}
+	data, err := encodeValues(session.Values)
+	if err != nil {
+		return err
+	}
+	if err = s.backend.Persist(session.ID, data, expiresAt(options)); err != nil {
+		return err
+	}
+
+	setCookie(ctx, session.Name(), session.ID, options)
+	return nil
+}
+
+// newSession returns a new session with a copy of options.
+func newSession(store sessions.Store, name string, options *sessions.Options) *sessions.Session {
+	session := sessions.NewSession(store, name)
+	opts := *options
+	session.Options = &opts
+	session.IsNew = true
+	return session
+}
+
+// sessionOptions returns the session's options, or the default options if it is nil.
+func sessionOptions(session *sessions.Session, defaults *sessions.Options) *sessions.Options {
+	if session.Options != nil {
+		return session.Options
+	}
+	return defaults
+}
+
+// expiresAt returns the expiration time of session,
+// the session without MaxAge expires after the default max age.
+func expiresAt(options *sessions.Options) time.Time {
+	maxAge := options.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultMaxAge
+	}
+	return timeNow().Add(time.Duration(maxAge) * time.Second)
+}
+
+// setCookie sets the session cookie, the cookie will be expired if MaxAge is negative.
+func setCookie(ctx *fasthttp.RequestCtx, name, value string, options *sessions.Options) {
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+
+	cookie.SetKey(name)
+	cookie.SetValue(value)
+	cookie.SetPath(options.Path)
+	cookie.SetDomain(options.Domain)
+	cookie.SetSecure(options.Secure)
+	cookie.SetHTTPOnly(options.HttpOnly)
+	cookie.SetSameSite(fasthttp.CookieSameSiteLaxMode)
+	if options.MaxAge > 0 {
+		cookie.SetMaxAge(options.MaxAge)
+		cookie.SetExpire(timeNow().Add(time.Duration(options.MaxAge) * time.Second))
+	} else if options.MaxAge < 0 {
+		cookie.SetExpire(fasthttp.CookieExpireDelete)
+	}
+
+	ctx.Response.Header.SetCookie(cookie)
+}
+
+// newID returns a random session ID.
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// validID reports whether the session ID is generated by newID,
+// so that it is safe to be used as file name.
+func validID(id string) bool {
+	if len(id) != 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeValues encodes the session values by gob,
+// the custom types of values should be registered by gob.Register.
+func encodeValues(values map[interface{}]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeValues decodes the data into session values.
+func decodeValues(data []byte, values map[interface{}]interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(&values)
+}
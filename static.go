@@ -0,0 +1,391 @@
+package clevergo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StaticConfig for serving static files.
+type StaticConfig struct {
+	IndexNames []string // Index files of directory, such as "index.html".
+	Browse     bool     // Whether to list the directory's files if there is no index file.
+	SPA        bool     // Whether to fall back to the root index file for the missing paths without extension.
+	Compressed bool     // Whether to serve the precompressed ".br" and ".gz" files.
+	MaxAge     int      // Cache-Control's max-age in seconds, zero means no Cache-Control header.
+}
+
+// NewStaticConfig returns default static configuration.
+func NewStaticConfig() *StaticConfig {
+	return &StaticConfig{
+		IndexNames: []string{"index.html"},
+		Browse:     false,
+		SPA:        false,
+		Compressed: true,
+	}
+}
+
+// Static serves files from the directory root under the prefix.
+//
+// For example, Static("/assets", "./public") serves "./public/css/app.css" at "/assets/css/app.css".
+func (r *Router) Static(prefix, root string) {
+	r.StaticFS(prefix, os.DirFS(root))
+}
+
+// StaticFS serves files from the file system under the prefix,
+// such as an embed.FS.
+func (r *Router) StaticFS(prefix string, fsys fs.FS) {
+	r.StaticFSWithConfig(prefix, fsys, NewStaticConfig())
+}
+
+// StaticFSWithConfig serves files from the file system under the prefix with custom configuration.
+//
+// The GET and HEAD handlers are registered by Router.Handle,
+// so that the router's middlewares are applied.
+func (r *Router) StaticFSWithConfig(prefix string, fsys fs.FS, config *StaticConfig) {
+	handler := &staticHandler{
+		fs:     fsys,
+		config: config,
+	}
+
+	route := strings.TrimRight(prefix, "/") + "/*filepath"
+	r.GET(route, handler)
+	r.HEAD(route, handler)
+}
+
+// staticHandler serves files from a file system.
+type staticHandler struct {
+	fs     fs.FS
+	config *StaticConfig
+}
+
+// Handle implemented Handler Interface.
+func (h *staticHandler) Handle(ctx *Context) {
+	filepath := ctx.Params.String("filepath")
+	name := strings.TrimPrefix(path.Clean("/"+filepath), "/")
+	if name == "" {
+		name = "."
+	}
+
+	info, err := fs.Stat(h.fs, name)
+	if err != nil {
+		if h.config.SPA && path.Ext(name) == "" {
+			h.serveIndex(ctx, ".")
+			return
+		}
+		ctx.NotFound()
+		return
+	}
+
+	if info.IsDir() {
+		// Redirect "/dir" to "/dir/", so that the relative links work.
+		if !strings.HasSuffix(filepath, "/") {
+			ctx.Redirect(fasthttp.StatusMovedPermanently, string(ctx.Path())+"/")
+			return
+		}
+		h.serveIndex(ctx, name)
+		return
+	}
+
+	h.serveFile(ctx, name, info)
+}
+
+// serveIndex serves the index file of the directory,
+// or lists the directory if browse is enabled.
+func (h *staticHandler) serveIndex(ctx *Context, dir string) {
+	for _, index := range h.config.IndexNames {
+		name := path.Join(dir, index)
+		if info, err := fs.Stat(h.fs, name); err == nil && !info.IsDir() {
+			h.serveFile(ctx, name, info)
+			return
+		}
+	}
+
+	if h.config.Browse {
+		h.serveDir(ctx, dir)
+		return
+	}
+
+	ctx.NotFound()
+}
+
+// serveDir lists the files of the directory.
+func (h *staticHandler) serveDir(ctx *Context, dir string) {
+	entries, err := fs.ReadDir(h.fs, dir)
+	if err != nil {
+		ctx.NotFound()
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<!doctype html>\n<pre>\n")
+	for _, entry := range entries {
+		name, href := entry.Name(), url.PathEscape(entry.Name())
+		if entry.IsDir() {
+			name, href = name+"/", href+"/"
+		}
+		fmt.Fprintf(&buf, "<a href=\"%s\">%s</a>\n", template.HTMLEscapeString(href), template.HTMLEscapeString(name))
+	}
+	buf.WriteString("</pre>\n")
+
+	ctx.SetContentTypeToHTML()
+	ctx.Response.SetBody(buf.Bytes())
+}
+
+// precompressedEncodings are the sidecar files' encodings in order of preference.
+var precompressedEncodings = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// serveFile serves the file, or its precompressed sidecar if the client accepts it.
+func (h *staticHandler) serveFile(ctx *Context, name string, info fs.FileInfo) {
+	contentType := mime.TypeByExtension(path.Ext(name))
+
+	if h.config.Compressed {
+		addVary(ctx, "Accept-Encoding")
+		for _, v := range precompressedEncodings {
+			if !ctx.Request.Header.HasAcceptEncoding(v.encoding) {
+				continue
+			}
+			sidecar, err := fs.Stat(h.fs, name+v.ext)
+			if err != nil || sidecar.IsDir() {
+				continue
+			}
+			if contentType == "" {
+				// The compressed content can not be sniffed.
+				contentType = "application/octet-stream"
+			}
+			ctx.Response.Header.Set("Content-Encoding", v.encoding)
+			name, info = name+v.ext, sidecar
+			break
+		}
+	}
+
+	f, err := h.fs.Open(name)
+	if err != nil {
+		ctx.NotFound()
+		return
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		// Some file systems' files are not seekable, read the whole file instead.
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	if h.config.MaxAge > 0 {
+		ctx.Response.Header.Set("Cache-Control", "public, max-age="+strconv.Itoa(h.config.MaxAge))
+	}
+
+	serveContent(ctx, contentType, info.ModTime(), info.Size(), content, modETag(info.ModTime(), info.Size()))
+}
+
+// serveContent responses the content with the Last-Modified and ETag headers,
+// and handles the conditional and Range requests.
+//
+// The Content-Type is sniffed from the content if contentType is empty.
+// The content will be closed after sending if it implements io.Closer.
+func serveContent(ctx *Context, contentType string, modtime time.Time, size int64, content io.ReadSeeker, etag string) {
+	streamed := false
+	if closer, ok := content.(io.Closer); ok {
+		defer func() {
+			if !streamed {
+				closer.Close()
+			}
+		}()
+	}
+
+	if etag != "" {
+		ctx.Response.Header.Set("ETag", etag)
+	}
+	if !isZeroTime(modtime) {
+		ctx.Response.Header.SetLastModified(modtime)
+	}
+
+	switch checkPreconditions(ctx, etag, modtime) {
+	case fasthttp.StatusNotModified:
+		writeNotModified(ctx)
+		return
+	case fasthttp.StatusPreconditionFailed:
+		ctx.Error(fasthttp.StatusMessage(fasthttp.StatusPreconditionFailed), fasthttp.StatusPreconditionFailed)
+		return
+	}
+
+	if contentType == "" {
+		var buf [512]byte
+		n, _ := io.ReadFull(content, buf[:])
+		contentType = http.DetectContentType(buf[:n])
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+			return
+		}
+	}
+	ctx.SetContentType(contentType)
+	ctx.Response.Header.Set("Accept-Ranges", "bytes")
+
+	start, length := int64(0), size
+	if rangeHeader := ctx.Request.Header.Peek("Range"); len(rangeHeader) > 0 && checkIfRange(ctx, etag, modtime) {
+		var err error
+		start, length, err = parseRange(string(rangeHeader), size)
+		if err == errInvalidRange {
+			// Ignore the malformed or multiple ranges and send the whole content.
+			start, length = 0, size
+		} else if err != nil {
+			ctx.Error(fasthttp.StatusMessage(fasthttp.StatusRequestedRangeNotSatisfiable), fasthttp.StatusRequestedRangeNotSatisfiable)
+			ctx.Response.Header.Set("Content-Range", "bytes */"+strconv.FormatInt(size, 10))
+			return
+		} else {
+			ctx.Response.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, size))
+			ctx.SetStatusCode(fasthttp.StatusPartialContent)
+		}
+	}
+
+	if ctx.IsHead() {
+		ctx.Response.ResetBody()
+		ctx.Response.Header.SetContentLength(int(length))
+		return
+	}
+
+	if _, err := content.Seek(start, io.SeekStart); err != nil {
+		ctx.Error(fasthttp.StatusMessage(fasthttp.StatusInternalServerError), fasthttp.StatusInternalServerError)
+		return
+	}
+	// The body stream will be closed by fasthttp after sending.
+	streamed = true
+	ctx.SetBodyStream(&limitedReadCloser{io.LimitReader(content, length), content}, int(length))
+}
+
+// limitedReadCloser reads the limited content and closes the underlying content.
+type limitedReadCloser struct {
+	io.Reader
+	content io.Reader
+}
+
+// Close closes the underlying content if it implements io.Closer.
+func (r *limitedReadCloser) Close() error {
+	if closer, ok := r.content.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// isZeroTime reports whether t is obviously unspecified.
+func isZeroTime(t time.Time) bool {
+	return t.IsZero() || t.Equal(time.Unix(0, 0))
+}
+
+// checkIfRange reports whether the Range header should be honoured,
+// according to the If-Range header.
+func checkIfRange(ctx *Context, etag string, modtime time.Time) bool {
+	ir := ctx.Request.Header.Peek("If-Range")
+	if len(ir) == 0 {
+		return true
+	}
+	if ir[0] == '"' || bytes.HasPrefix(ir, []byte("W/")) {
+		return etag != "" && etagMatch(string(ir), etag, false)
+	}
+	t, err := fasthttp.ParseHTTPDate(ir)
+	return err == nil && !isZeroTime(modtime) && modtime.Truncate(time.Second).Equal(t)
+}
+
+// etagMatch reports whether the etag matches one of the entity tags in the list,
+// using the weak comparison if weak is true, or the strong comparison otherwise.
+func etagMatch(list, etag string, weak bool) bool {
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	}
+
+	for _, v := range strings.Split(list, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" {
+			return true
+		}
+		if weak {
+			v = strings.TrimPrefix(v, "W/")
+		}
+		// The weak entity tags never match in the strong comparison.
+		if v == etag && !strings.HasPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	errInvalidRange       = errors.New("invalid range")
+	errUnsatisfiableRange = errors.New("unsatisfiable range")
+)
+
+// parseRange parses the single byte range of the Range header,
+// and returns the start position and the length of range.
+func parseRange(s string, size int64) (int64, int64, error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(s, prefix) {
+		return 0, 0, errInvalidRange
+	}
+	s = strings.TrimSpace(s[len(prefix):])
+	if strings.Contains(s, ",") {
+		return 0, 0, errInvalidRange
+	}
+
+	i := strings.Index(s, "-")
+	if i < 0 {
+		return 0, 0, errInvalidRange
+	}
+	first, last := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+
+	if first == "" {
+		// Suffix range, such as "bytes=-500" means the final 500 bytes.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, errInvalidRange
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, errUnsatisfiableRange
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, errInvalidRange
+	}
+	if start >= size {
+		return 0, 0, errUnsatisfiableRange
+	}
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, 0, errInvalidRange
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end - start + 1, nil
+}
@@ -0,0 +1,245 @@
+package clevergo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var staticModTime = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func newStaticFS() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":       {Data: []byte("<h1>Index</h1>"), ModTime: staticModTime},
+		"css/app.css":      {Data: []byte("body{margin:0}"), ModTime: staticModTime},
+		"js/app.js":        {Data: []byte("console.log('plain')"), ModTime: staticModTime},
+		"js/app.js.gz":     {Data: []byte("gzipped"), ModTime: staticModTime},
+		"js/app.js.br":     {Data: []byte("brotli"), ModTime: staticModTime},
+		"docs/readme.txt":  {Data: []byte("0123456789"), ModTime: staticModTime},
+		"docs/<b>old.txt":  {Data: []byte("old"), ModTime: staticModTime},
+		"empty/.gitignore": {Data: []byte(""), ModTime: staticModTime},
+	}
+}
+
+func TestRouter_StaticFS(t *testing.T) {
+	r := NewRouter()
+	r.AddMiddleware(simpleMiddleware{})
+	r.StaticFS("/assets", newStaticFS())
+
+	resp := serve(t, r.Handler, "GET /assets/css/app.css HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 200 {
+		t.Fatalf("Unexpected status code %d. Expected %d", resp.StatusCode(), 200)
+	}
+	if !bytes.Equal(resp.Body(), []byte("body{margin:0}")) {
+		t.Errorf("Unexpected body %q", resp.Body())
+	}
+	if ct := string(resp.Header.ContentType()); !strings.HasPrefix(ct, "text/css") {
+		t.Errorf("Unexpected Content-Type %q", ct)
+	}
+	if !bytes.Equal(resp.Header.Peek("Middleware"), []byte("Simple")) {
+		t.Errorf("Router's middlewares should be applied to static files")
+	}
+	if len(resp.Header.Peek("ETag")) == 0 || len(resp.Header.Peek("Last-Modified")) == 0 {
+		t.Errorf("Expected ETag and Last-Modified headers")
+	}
+
+	resp = serve(t, r.Handler, "GET /assets/ HTTP/1.1\r\n\r\n")
+	if !bytes.Equal(resp.Body(), []byte("<h1>Index</h1>")) {
+		t.Errorf("Unexpected index body %q", resp.Body())
+	}
+
+	resp = serve(t, r.Handler, "GET /assets/../../etc/passwd HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 404 {
+		t.Errorf("Unexpected status code %d. Expected %d", resp.StatusCode(), 404)
+	}
+
+	resp = serve(t, r.Handler, "GET /assets/missing HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 404 {
+		t.Errorf("Unexpected status code %d. Expected %d", resp.StatusCode(), 404)
+	}
+
+	resp = serve(t, r.Handler, "HEAD /assets/docs/readme.txt HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 200 || resp.Header.ContentLength() != 10 {
+		t.Errorf("Unexpected HEAD response: %d, Content-Length %d", resp.StatusCode(), resp.Header.ContentLength())
+	}
+}
+
+func TestRouter_StaticConditional(t *testing.T) {
+	r := NewRouter()
+	r.StaticFS("/", newStaticFS())
+
+	resp := serve(t, r.Handler, "GET /docs/readme.txt HTTP/1.1\r\n\r\n")
+	etag := string(resp.Header.Peek("ETag"))
+	lastModified := string(resp.Header.Peek("Last-Modified"))
+
+	resp = serve(t, r.Handler, "GET /docs/readme.txt HTTP/1.1\r\nIf-None-Match: "+etag+"\r\n\r\n")
+	if resp.StatusCode() != 304 {
+		t.Errorf("Unexpected status code %d. Expected %d", resp.StatusCode(), 304)
+	}
+
+	resp = serve(t, r.Handler, "GET /docs/readme.txt HTTP/1.1\r\nIf-None-Match: \"other\"\r\n\r\n")
+	if resp.StatusCode() != 200 {
+		t.Errorf("Unexpected status code %d. Expected %d", resp.StatusCode(), 200)
+	}
+
+	resp = serve(t, r.Handler, "GET /docs/readme.txt HTTP/1.1\r\nIf-Modified-Since: "+lastModified+"\r\n\r\n")
+	if resp.StatusCode() != 304 {
+		t.Errorf("Unexpected status code %d. Expected %d", resp.StatusCode(), 304)
+	}
+}
+
+func TestRouter_StaticRange(t *testing.T) {
+	r := NewRouter()
+	r.StaticFS("/", newStaticFS())
+
+	tests := []struct {
+		rangeHeader  string
+		code         int
+		body         string
+		contentRange string
+	}{
+		{"bytes=2-5", 206, "2345", "bytes 2-5/10"},
+		{"bytes=7-", 206, "789", "bytes 7-9/10"},
+		{"bytes=-3", 206, "789", "bytes 7-9/10"},
+		{"bytes=5-100", 206, "56789", "bytes 5-9/10"},
+		{"bytes=20-30", 416, "", "bytes */10"},
+		{"bytes=0-1,3-4", 200, "0123456789", ""},
+		{"items=0-1", 200, "0123456789", ""},
+	}
+
+	for _, test := range tests {
+		resp := serve(t, r.Handler, "GET /docs/readme.txt HTTP/1.1\r\nRange: "+test.rangeHeader+"\r\n\r\n")
+		if resp.StatusCode() != test.code {
+			t.Errorf("Range %q: unexpected status code %d. Expected %d", test.rangeHeader, resp.StatusCode(), test.code)
+		}
+		if test.code != 416 && string(resp.Body()) != test.body {
+			t.Errorf("Range %q: unexpected body %q. Expected %q", test.rangeHeader, resp.Body(), test.body)
+		}
+		if cr := string(resp.Header.Peek("Content-Range")); cr != test.contentRange {
+			t.Errorf("Range %q: unexpected Content-Range %q. Expected %q", test.rangeHeader, cr, test.contentRange)
+		}
+	}
+
+	// The Range header is ignored if If-Range doesn't match.
+	resp := serve(t, r.Handler, "GET /docs/readme.txt HTTP/1.1\r\nRange: bytes=2-5\r\nIf-Range: \"stale\"\r\n\r\n")
+	if resp.StatusCode() != 200 {
+		t.Errorf("Unexpected status code %d. Expected %d", resp.StatusCode(), 200)
+	}
+}
+
+func TestRouter_StaticPrecompressed(t *testing.T) {
+	r := NewRouter()
+	r.StaticFS("/", newStaticFS())
+
+	tests := []struct {
+		acceptEncoding  string
+		contentEncoding string
+		body            string
+	}{
+		{"gzip, deflate, br", "br", "brotli"},
+		{"gzip", "gzip", "gzipped"},
+		{"", "", "console.log('plain')"},
+	}
+
+	for _, test := range tests {
+		req := "GET /js/app.js HTTP/1.1\r\n"
+		if test.acceptEncoding != "" {
+			req += "Accept-Encoding: " + test.acceptEncoding + "\r\n"
+		}
+		resp := serve(t, r.Handler, req+"\r\n")
+		if ce := string(resp.Header.Peek("Content-Encoding")); ce != test.contentEncoding {
+			t.Errorf("Unexpected Content-Encoding %q. Expected %q", ce, test.contentEncoding)
+		}
+		if string(resp.Body()) != test.body {
+			t.Errorf("Unexpected body %q. Expected %q", resp.Body(), test.body)
+		}
+		if ct := string(resp.Header.ContentType()); !strings.Contains(ct, "javascript") {
+			t.Errorf("Unexpected Content-Type %q", ct)
+		}
+		if !strings.Contains(string(resp.Header.Peek("Vary")), "Accept-Encoding") {
+			t.Errorf("Expected Vary: Accept-Encoding")
+		}
+	}
+}
+
+func TestRouter_StaticBrowseAndSPA(t *testing.T) {
+	r := NewRouter()
+	r.StaticFS("/", newStaticFS())
+
+	resp := serve(t, r.Handler, "GET /docs/ HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 404 {
+		t.Errorf("Directory listing should be disabled by default, got status code %d", resp.StatusCode())
+	}
+
+	resp = serve(t, r.Handler, "GET /docs HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 301 || !strings.HasSuffix(string(resp.Header.Peek("Location")), "/docs/") {
+		t.Errorf("Unexpected redirect %d %q", resp.StatusCode(), resp.Header.Peek("Location"))
+	}
+
+	config := NewStaticConfig()
+	config.Browse = true
+	config.SPA = true
+	r = NewRouter()
+	r.StaticFSWithConfig("/app", newStaticFS(), config)
+
+	resp = serve(t, r.Handler, "GET /app/docs/ HTTP/1.1\r\n\r\n")
+	body := string(resp.Body())
+	if !strings.Contains(body, `<a href="readme.txt">readme.txt</a>`) {
+		t.Errorf("Unexpected directory listing %q", body)
+	}
+	if strings.Contains(body, "<b>") {
+		t.Errorf("File names should be escaped: %q", body)
+	}
+
+	resp = serve(t, r.Handler, "GET /app/users/42 HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 200 || string(resp.Body()) != "<h1>Index</h1>" {
+		t.Errorf("Unexpected SPA fallback %d %q", resp.StatusCode(), resp.Body())
+	}
+
+	resp = serve(t, r.Handler, "GET /app/missing.js HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 404 {
+		t.Errorf("Missing files with extension should not fall back, got status code %d", resp.StatusCode())
+	}
+}
+
+func TestRouter_Static(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("Hello CleverGo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRouter()
+	r.Static("/public/", dir)
+
+	resp := serve(t, r.Handler, "GET /public/hello.txt HTTP/1.1\r\n\r\n")
+	if !bytes.Equal(resp.Body(), []byte("Hello CleverGo")) {
+		t.Errorf("Unexpected body %q", resp.Body())
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header        string
+		start, length int64
+		err           error
+	}{
+		{"bytes=0-0", 0, 1, nil},
+		{"bytes=0-", 0, 10, nil},
+		{"bytes=-0", 0, 0, errUnsatisfiableRange},
+		{"bytes=10-", 0, 0, errUnsatisfiableRange},
+		{"bytes=5-2", 0, 0, errInvalidRange},
+		{"bytes=a-b", 0, 0, errInvalidRange},
+		{"bytes=1", 0, 0, errInvalidRange},
+	}
+
+	for _, test := range tests {
+		start, length, err := parseRange(test.header, 10)
+		if start != test.start || length != test.length || err != test.err {
+			t.Errorf("parseRange(%q) = %d, %d, %v, expect %d, %d, %v", test.header, start, length, err, test.start, test.length, test.err)
+		}
+	}
+}
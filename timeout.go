@@ -0,0 +1,54 @@
+package clevergo
+
+import (
+	"context"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+// TimeoutConfig for TimeoutMiddleware.
+type TimeoutConfig struct {
+	Timeout    time.Duration // Timeout of request.
+	StatusCode int           // Status code of timed out request, 503 or 504.
+}
+
+// NewTimeoutConfig returns default timeout configuration, which responses 503 on timeout.
+func NewTimeoutConfig(timeout time.Duration) *TimeoutConfig {
+	return &TimeoutConfig{
+		Timeout:    timeout,
+		StatusCode: fasthttp.StatusServiceUnavailable,
+	}
+}
+
+// TimeoutMiddleware sets the deadline of Context.Context, the timed out requests will be
+// handled by Context.HandleError with the status code and context.DeadlineExceeded.
+//
+// The handler is not interrupted, it must watch Context.Context().Done() and stop working when it is done,
+// such as passing Context.Context to the downstream calls. The response is not sent until the handler returns,
+// and then the response of the timed out handler is replaced by the error response.
+type TimeoutMiddleware struct {
+	config *TimeoutConfig
+}
+
+// NewTimeoutMiddleware returns a TimeoutMiddleware's instance.
+func NewTimeoutMiddleware(config *TimeoutConfig) *TimeoutMiddleware {
+	return &TimeoutMiddleware{config: config}
+}
+
+// Handle implemented Middleware Interface.
+func (m *TimeoutMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		parent := ctx.Context()
+		c, cancel := context.WithTimeout(parent, m.config.Timeout)
+		defer cancel()
+		ctx.SetContext(c)
+
+		next.Handle(ctx)
+
+		// Restore the parent context, since the current one is about to be canceled.
+		ctx.SetContext(parent)
+		if c.Err() == context.DeadlineExceeded {
+			ctx.HandleError(m.config.StatusCode, context.DeadlineExceeded)
+		}
+	})
+}
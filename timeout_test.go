@@ -0,0 +1,87 @@
+package clevergo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type testContextKey struct{}
+
+type contextValueMiddleware struct{}
+
+func (m contextValueMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		ctx.WithValue(testContextKey{}, "foo")
+		next.Handle(ctx)
+	})
+}
+
+func TestContext_Context(t *testing.T) {
+	var c context.Context
+	r := NewRouter()
+	r.AddMiddleware(contextValueMiddleware{})
+	r.GET("/", HandlerFunc(func(ctx *Context) {
+		c = ctx.Context()
+		if c != ctx.Context() {
+			t.Error("expected the same context")
+		}
+		if err := c.Err(); err != nil {
+			t.Errorf("unexpected error %v", err)
+		}
+		ctx.Text(c.Value(testContextKey{}))
+	}))
+
+	resp := serve(t, r.Handler, "GET / HTTP/1.1\r\n\r\n")
+	if string(resp.Body()) != "foo" {
+		t.Errorf("unexpected body %q", resp.Body())
+	}
+	// The context is canceled after the request is finished.
+	if c.Err() != context.Canceled {
+		t.Errorf("expected error %v, got %v", context.Canceled, c.Err())
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	var handled error
+	r := NewRouter()
+	r.SetErrorHandler(func(ctx *Context, code int, err error) {
+		handled = err
+		ctx.Error(err.Error(), code)
+	})
+	r.AddMiddleware(NewTimeoutMiddleware(NewTimeoutConfig(20 * time.Millisecond)))
+	r.GET("/slow", HandlerFunc(func(ctx *Context) {
+		if _, ok := ctx.Context().Deadline(); !ok {
+			t.Error("expected a deadline")
+		}
+		select {
+		case <-ctx.Context().Done():
+		case <-time.After(time.Second):
+		}
+		ctx.Text("slow")
+	}))
+	r.GET("/fast", HandlerFunc(func(ctx *Context) {
+		ctx.Text("fast")
+	}))
+
+	resp := serve(t, r.Handler, "GET /fast HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 200 || string(resp.Body()) != "fast" {
+		t.Errorf("unexpected response %d, %q", resp.StatusCode(), resp.Body())
+	}
+
+	resp = serve(t, r.Handler, "GET /slow HTTP/1.1\r\n\r\n")
+	if resp.StatusCode() != 503 || handled != context.DeadlineExceeded {
+		t.Errorf("unexpected response %d, %v", resp.StatusCode(), handled)
+	}
+
+	config := NewTimeoutConfig(20 * time.Millisecond)
+	config.StatusCode = 504
+	r = NewRouter()
+	r.AddMiddleware(NewTimeoutMiddleware(config))
+	r.GET("/", HandlerFunc(func(ctx *Context) {
+		<-ctx.Context().Done()
+	}))
+	if resp = serve(t, r.Handler, "GET / HTTP/1.1\r\n\r\n"); resp.StatusCode() != 504 {
+		t.Errorf("unexpected status code %d", resp.StatusCode())
+	}
+}
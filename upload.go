@@ -0,0 +1,388 @@
+package clevergo
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUploadTooLarge is returned when the request body exceeds the max size.
+	ErrUploadTooLarge = errors.New("upload too large")
+	// ErrUploadTooManyFiles is returned when the request contains too many files.
+	ErrUploadTooManyFiles = errors.New("too many uploaded files")
+	// ErrUploadType is returned when the sniffed MIME type of file is not allowed.
+	ErrUploadType = errors.New("upload type not allowed")
+	// ErrUploadExtension is returned when the extension of file is not allowed.
+	ErrUploadExtension = errors.New("upload extension not allowed")
+	// ErrUploadName is returned when the name of stored file is invalid.
+	ErrUploadName = errors.New("invalid upload name")
+	// ErrNoUploadStorage is returned when saving file without storage, see UploadMiddleware.
+	ErrNoUploadStorage = errors.New("no upload storage")
+)
+
+// UploadStorage stores the uploaded files by name, the names are slash-separated paths.
+type UploadStorage interface {
+	Save(name string, r io.Reader) error
+	Open(name string) (io.ReadCloser, error)
+	Remove(name string) error
+}
+
+// LocalUploadStorage stores the files in the local directory.
+type LocalUploadStorage struct {
+	dir string
+}
+
+// NewLocalUploadStorage returns a LocalUploadStorage's instance.
+func NewLocalUploadStorage(dir string) *LocalUploadStorage {
+	return &LocalUploadStorage{dir: dir}
+}
+
+// path returns the file path of name, the name can not escape from the directory.
+func (s *LocalUploadStorage) path(name string) (string, error) {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	if name == "/" {
+		return "", ErrUploadName
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(name)), nil
+}
+
+// Save implemented UploadStorage Interface.
+//
+// The file is written to a temporary file and then renamed, so that the partial files are never visible.
+func (s *LocalUploadStorage) Save(name string, r io.Reader) error {
+	p, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), p)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Open implemented UploadStorage Interface.
+func (s *LocalUploadStorage) Open(name string) (io.ReadCloser, error) {
+	p, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// Remove implemented UploadStorage Interface.
+func (s *LocalUploadStorage) Remove(name string) error {
+	p, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(p)
+}
+
+// MemoryUploadStorage stores the files in memory, it is useful for testing.
+type MemoryUploadStorage struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+// NewMemoryUploadStorage returns a MemoryUploadStorage's instance.
+func NewMemoryUploadStorage() *MemoryUploadStorage {
+	return &MemoryUploadStorage{files: make(map[string][]byte)}
+}
+
+// Save implemented UploadStorage Interface.
+func (s *MemoryUploadStorage) Save(name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.files[name] = data
+	s.mu.Unlock()
+	return nil
+}
+
+// Open implemented UploadStorage Interface.
+func (s *MemoryUploadStorage) Open(name string) (io.ReadCloser, error) {
+	s.mu.RLock()
+	data, ok := s.files[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Remove implemented UploadStorage Interface.
+func (s *MemoryUploadStorage) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.files[name]; !ok {
+		return fs.ErrNotExist
+	}
+	delete(s.files, name)
+	return nil
+}
+
+// UploadConfig for UploadMiddleware.
+type UploadConfig struct {
+	MaxSize           int64         // Max size of request body, zero means unlimited, see NewUploadConfig.
+	MaxFiles          int           // Max count of files, zero means unlimited.
+	MaxMemory         int64         // Max size of form stored in memory, the rest of files are stored in temporary files.
+	AllowedTypes      []string      // Allowed sniffed MIME types, such as "image/png" and "image/*", empty means all.
+	AllowedExtensions []string      // Allowed extensions, such as ".png", empty means all.
+	Storage           UploadStorage // Storage of Context.SaveUploadedFile.
+}
+
+// NewUploadConfig returns default upload configuration, which accepts up to 10 files in 4MB,
+// the same as fasthttp's default max request body size.
+//
+// The larger bodies are rejected by the server before the middleware, unless Config.ServerStreamRequestBody
+// is enabled, or Config.ServerMaxRequestBodySize is raised as well.
+func NewUploadConfig(storage UploadStorage) *UploadConfig {
+	return &UploadConfig{
+		MaxSize:   fasthttp.DefaultMaxRequestBodySize,
+		MaxFiles:  10,
+		MaxMemory: 1 << 20,
+		Storage:   storage,
+	}
+}
+
+// UploadMiddleware parses the multipart form with the limits, and checks the files
+// before handling, the rejected requests are handled by Context.HandleError with
+// status code 413 or 415, and 400 for the malformed forms.
+//
+// The form is parsed from the body stream if Config.ServerStreamRequestBody is enabled,
+// so that the body is not buffered in memory, and the temporary files are removed after handling.
+// It is intended to be applied to the upload routes, see Chain and RouteGroup.
+type UploadMiddleware struct {
+	config *UploadConfig
+}
+
+// NewUploadMiddleware returns an UploadMiddleware's instance.
+func NewUploadMiddleware(config *UploadConfig) *UploadMiddleware {
+	return &UploadMiddleware{config: config}
+}
+
+// Handle implemented Middleware Interface.
+func (m *UploadMiddleware) Handle(next Handler) Handler {
+	return HandlerFunc(func(ctx *Context) {
+		ctx.uploadConfig = m.config
+		boundary := ctx.Request.Header.MultipartFormBoundary()
+		if len(boundary) == 0 {
+			next.Handle(ctx)
+			return
+		}
+
+		form, err := m.parse(ctx, string(boundary))
+		if form != nil {
+			defer form.RemoveAll()
+		}
+		if err == nil {
+			err = m.check(form)
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrUploadTooLarge), errors.Is(err, ErrUploadTooManyFiles):
+			ctx.HandleError(fasthttp.StatusRequestEntityTooLarge, err)
+			return
+		case errors.Is(err, ErrUploadType), errors.Is(err, ErrUploadExtension):
+			ctx.HandleError(fasthttp.StatusUnsupportedMediaType, err)
+			return
+		default:
+			ctx.HandleError(fasthttp.StatusBadRequest, err)
+			return
+		}
+
+		ctx.uploadForm = form
+		next.Handle(ctx)
+	})
+}
+
+func (m *UploadMiddleware) parse(ctx *Context, boundary string) (*multipart.Form, error) {
+	if m.config.MaxSize > 0 && int64(ctx.Request.Header.ContentLength()) > m.config.MaxSize {
+		return nil, ErrUploadTooLarge
+	}
+
+	var body io.Reader
+	if ctx.Request.IsBodyStream() {
+		body = ctx.Request.BodyStream()
+	} else {
+		body = bytes.NewReader(ctx.Request.Body())
+	}
+	if m.config.MaxSize > 0 {
+		body = &uploadLimitReader{r: body, n: m.config.MaxSize}
+	}
+	return multipart.NewReader(body, boundary).ReadForm(m.config.MaxMemory)
+}
+
+// check checks the count, extensions and sniffed MIME types of files,
+// the Content-Type of file header is replaced by the sniffed MIME type.
+func (m *UploadMiddleware) check(form *multipart.Form) error {
+	count := 0
+	for _, files := range form.File {
+		count += len(files)
+		if m.config.MaxFiles > 0 && count > m.config.MaxFiles {
+			return ErrUploadTooManyFiles
+		}
+		for _, file := range files {
+			if !m.allowExtension(file.Filename) {
+				return ErrUploadExtension
+			}
+			contentType, err := sniffFile(file)
+			if err != nil {
+				return err
+			}
+			if !m.allowType(contentType) {
+				return ErrUploadType
+			}
+			file.Header.Set("Content-Type", contentType)
+		}
+	}
+	return nil
+}
+
+func (m *UploadMiddleware) allowExtension(filename string) bool {
+	if len(m.config.AllowedExtensions) == 0 {
+		return true
+	}
+	ext := filepath.Ext(filename)
+	for _, allowed := range m.config.AllowedExtensions {
+		if strings.EqualFold(ext, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *UploadMiddleware) allowType(contentType string) bool {
+	if len(m.config.AllowedTypes) == 0 {
+		return true
+	}
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	for _, allowed := range m.config.AllowedTypes {
+		if strings.HasSuffix(allowed, "/*") && strings.HasPrefix(contentType, allowed[:len(allowed)-1]) ||
+			strings.EqualFold(contentType, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffFile detects the MIME type of file by its content.
+func sniffFile(file *multipart.FileHeader) (string, error) {
+	f, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// uploadLimitReader returns ErrUploadTooLarge if there are more than n bytes.
+type uploadLimitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *uploadLimitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		var b [1]byte
+		if n, _ := l.r.Read(b[:]); n > 0 {
+			return 0, ErrUploadTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// multipartForm returns the form parsed by UploadMiddleware, or parses it by fasthttp.
+func (ctx *Context) multipartForm() (*multipart.Form, error) {
+	if ctx.uploadForm != nil {
+		return ctx.uploadForm, nil
+	}
+	return ctx.MultipartForm()
+}
+
+// FormFile returns the first file of the form field.
+//
+// If the route is applied UploadMiddleware, the file has been checked, and its
+// Content-Type header is the sniffed MIME type.
+func (ctx *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	ctx.checkReleased()
+	files := ctx.FormFiles(name)
+	if len(files) == 0 {
+		return nil, fasthttp.ErrMissingFile
+	}
+	return files[0], nil
+}
+
+// FormFiles returns all files of the form field.
+func (ctx *Context) FormFiles(name string) []*multipart.FileHeader {
+	ctx.checkReleased()
+	form, err := ctx.multipartForm()
+	if err != nil {
+		return nil
+	}
+	return form.File[name]
+}
+
+// SaveUploadedFile saves the file to the storage of UploadMiddleware, and returns the stored name.
+//
+// If the name is empty, a random name with the file's extension is generated.
+func (ctx *Context) SaveUploadedFile(file *multipart.FileHeader, name string) (string, error) {
+	ctx.checkReleased()
+	if ctx.uploadConfig == nil || ctx.uploadConfig.Storage == nil {
+		return "", ErrNoUploadStorage
+	}
+	if name == "" {
+		name = hex.EncodeToString(generateToken(16)) + strings.ToLower(filepath.Ext(file.Filename))
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if err = ctx.uploadConfig.Storage.Save(name, f); err != nil {
+		return "", err
+	}
+	return name, nil
+}
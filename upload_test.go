@@ -0,0 +1,210 @@
+package clevergo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// newUploadRequest returns a multipart request with the files, the keys are file names.
+func newUploadRequest(files map[string][]byte, fields map[string]string) string {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	w.SetBoundary("boundary")
+	for name, value := range fields {
+		w.WriteField(name, value)
+	}
+	for filename, content := range files {
+		part, _ := w.CreateFormFile("file", filename)
+		part.Write(content)
+	}
+	w.Close()
+	return "POST /upload HTTP/1.1\r\nContent-Type: " + w.FormDataContentType() +
+		"\r\nContent-Length: " + strconv.Itoa(body.Len()) + "\r\n\r\n" + body.String()
+}
+
+func TestUploadMiddleware(t *testing.T) {
+	storage := NewMemoryUploadStorage()
+	config := NewUploadConfig(storage)
+	config.MaxSize = 1024
+	config.MaxFiles = 2
+	config.AllowedTypes = []string{"image/*"}
+	config.AllowedExtensions = []string{".png", ".jpg"}
+
+	var (
+		names       []string
+		contentType string
+		title       string
+	)
+	r := NewRouter()
+	r.POST("/upload", Chain(HandlerFunc(func(ctx *Context) {
+		names = names[:0]
+		title = ctx.Form("title")
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			ctx.Error(err.Error(), fasthttp.StatusBadRequest)
+			return
+		}
+		contentType = file.Header.Get("Content-Type")
+		for _, file := range ctx.FormFiles("file") {
+			name, err := ctx.SaveUploadedFile(file, "")
+			if err != nil {
+				ctx.Error(err.Error(), fasthttp.StatusInternalServerError)
+				return
+			}
+			names = append(names, name)
+		}
+	}), NewUploadMiddleware(config)))
+
+	resp := serve(t, r.Handler, newUploadRequest(map[string][]byte{"a.PNG": testPNG}, map[string]string{"title": "foo"}))
+	if resp.StatusCode() != 200 || len(names) != 1 || filepath.Ext(names[0]) != ".png" {
+		t.Fatalf("unexpected response %d %q, names %v", resp.StatusCode(), resp.Body(), names)
+	}
+	if contentType != "image/png" || title != "foo" {
+		t.Errorf("unexpected content type %q and title %q", contentType, title)
+	}
+	f, err := storage.Open(names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(f); !bytes.Equal(data, testPNG) {
+		t.Errorf("unexpected stored file %q", data)
+	}
+
+	tests := []struct {
+		files map[string][]byte
+		code  int
+	}{
+		{map[string][]byte{"a.png": testPNG, "b.png": testPNG}, 200},
+		{map[string][]byte{"a.png": testPNG, "b.png": testPNG, "c.png": testPNG}, 413},
+		{map[string][]byte{"a.png": bytes.Repeat(testPNG, 100)}, 413},
+		{map[string][]byte{"a.gif": testPNG}, 415},
+		{map[string][]byte{"a.png": []byte("<html></html>")}, 415},
+		{nil, 400},
+	}
+	for i, test := range tests {
+		resp = serve(t, r.Handler, newUploadRequest(test.files, nil))
+		if resp.StatusCode() != test.code {
+			t.Errorf("%d: unexpected status code %d. Expected %d", i, resp.StatusCode(), test.code)
+		}
+	}
+}
+
+func TestUploadMiddleware_Chunked(t *testing.T) {
+	config := NewUploadConfig(NewMemoryUploadStorage())
+	config.MaxSize = 1024
+	var handled error
+	r := NewRouter()
+	r.SetErrorHandler(func(ctx *Context, code int, err error) {
+		handled = err
+		ctx.Error(err.Error(), code)
+	})
+	r.POST("/upload", Chain(HandlerFunc(func(ctx *Context) {}), NewUploadMiddleware(config)))
+
+	for _, stream := range []bool{false, true} {
+		// The chunked body has no Content-Length, it is limited while parsing.
+		request := newUploadRequest(map[string][]byte{"a.png": bytes.Repeat(testPNG, 100)}, nil)
+		i := strings.Index(request, "\r\n\r\n")
+		head, body := request[:i], request[i+4:]
+		head = head[:strings.Index(head, "\r\nContent-Length")]
+		request = head + "\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n" + strconv.FormatInt(int64(len(body)), 16) + "\r\n" + body + "\r\n0\r\n\r\n"
+
+		handled = nil
+		s := &fasthttp.Server{Handler: r.Handler, StreamRequestBody: stream}
+		rw := &readWriter{}
+		rw.r.WriteString(request)
+		if err := s.ServeConn(rw); err != nil {
+			t.Fatal(err)
+		}
+		var resp fasthttp.Response
+		if err := resp.Read(bufio.NewReader(&rw.w)); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode() != 413 || !errors.Is(handled, ErrUploadTooLarge) {
+			t.Errorf("stream %t: unexpected response %d, %v", stream, resp.StatusCode(), handled)
+		}
+	}
+}
+
+func TestUploadMiddleware_Stream(t *testing.T) {
+	dir := t.TempDir()
+	config := NewUploadConfig(NewLocalUploadStorage(dir))
+	config.MaxMemory = 16
+
+	var saveErr error
+	r := NewRouter()
+	r.POST("/upload", Chain(HandlerFunc(func(ctx *Context) {
+		file, err := ctx.FormFile("file")
+		if err != nil {
+			saveErr = err
+			return
+		}
+		_, saveErr = ctx.SaveUploadedFile(file, "images/a.png")
+	}), NewUploadMiddleware(config)))
+
+	// The body is larger than the server's limit, it is only accepted by streaming.
+	content := bytes.Repeat(testPNG, 1024)
+	s := &fasthttp.Server{Handler: r.Handler, StreamRequestBody: true, MaxRequestBodySize: 4096}
+	rw := &readWriter{}
+	rw.r.WriteString(newUploadRequest(map[string][]byte{"a.png": content}, nil))
+	ch := make(chan error)
+	go func() {
+		ch <- s.ServeConn(rw)
+	}()
+	select {
+	case err := <-ch:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+
+	if saveErr != nil {
+		t.Fatal(saveErr)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "images", "a.png"))
+	if err != nil || !bytes.Equal(data, content) {
+		t.Errorf("unexpected stored file, %v", err)
+	}
+}
+
+func TestLocalUploadStorage(t *testing.T) {
+	dir := t.TempDir()
+	storage := NewLocalUploadStorage(filepath.Join(dir, "uploads"))
+	if err := storage.Save("../../escape.txt", bytes.NewReader([]byte("foo"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "uploads", "escape.txt")); err != nil {
+		t.Errorf("the name should not escape from the directory: %v", err)
+	}
+	if err := storage.Save("/", bytes.NewReader(nil)); !errors.Is(err, ErrUploadName) {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := storage.Remove("escape.txt"); err != nil {
+		t.Error(err)
+	}
+	if _, err := storage.Open("escape.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestContext_SaveUploadedFileWithoutStorage(t *testing.T) {
+	ctx := NewContext(NewRouter(), &fasthttp.RequestCtx{}, nil)
+	defer ctx.Close()
+	if _, err := ctx.SaveUploadedFile(&multipart.FileHeader{}, "a.png"); !errors.Is(err, ErrNoUploadStorage) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
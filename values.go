@@ -0,0 +1,27 @@
+package clevergo
+
+// Set stores the value of key in the current request, such as the user, tenant and locale
+// computed by middlewares.
+//
+// The values are removed when the Context is closed.
+func Set[T any](ctx *Context, key string, value T) {
+	ctx.checkReleased()
+	if ctx.values == nil {
+		ctx.values = make(map[string]interface{})
+	}
+	ctx.values[key] = value
+}
+
+// Get returns the value of key in the current request,
+// the ok is false if the key doesn't exist or the value's type is not T.
+func Get[T any](ctx *Context, key string) (value T, ok bool) {
+	ctx.checkReleased()
+	value, ok = ctx.values[key].(T)
+	return
+}
+
+// Delete removes the value of key in the current request.
+func Delete(ctx *Context, key string) {
+	ctx.checkReleased()
+	delete(ctx.values, key)
+}
@@ -0,0 +1,38 @@
+package clevergo
+
+import "testing"
+
+type testUser struct {
+	Name string
+}
+
+func TestSetAndGet(t *testing.T) {
+	ctx := NewContext(NewRouter(), nil, nil)
+	Set(ctx, "user", &testUser{Name: "foo"})
+	Set[int](ctx, "tenant", 1)
+
+	if user, ok := Get[*testUser](ctx, "user"); !ok || user.Name != "foo" {
+		t.Errorf("unexpected user %v, %t", user, ok)
+	}
+	if tenant, ok := Get[int](ctx, "tenant"); !ok || tenant != 1 {
+		t.Errorf("unexpected tenant %v, %t", tenant, ok)
+	}
+	// Mismatched type.
+	if tenant, ok := Get[string](ctx, "tenant"); ok || tenant != "" {
+		t.Errorf("unexpected tenant %q, %t", tenant, ok)
+	}
+	if _, ok := Get[int](ctx, "missing"); ok {
+		t.Error("expected missing value")
+	}
+
+	Delete(ctx, "tenant")
+	if _, ok := Get[int](ctx, "tenant"); ok {
+		t.Error("expected deleted value")
+	}
+
+	// The values are removed on closing, so that they are not leaked to the next request.
+	ctx.Close()
+	if _, ok := Get[*testUser](ctx, "user"); ok {
+		t.Error("expected the values to be reset")
+	}
+}